@@ -0,0 +1,459 @@
+package fakeslack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/slack-go/slack"
+)
+
+// Errors returned like the slack web api does
+var (
+	ErrChannelNotFound = errors.New("channel_not_found")
+	ErrMessageNotFound = errors.New("message_not_found")
+	ErrUserNotFound    = errors.New("user_not_found")
+	ErrAlreadyReacted  = errors.New("already_reacted")
+	ErrNoReaction      = errors.New("no_reaction")
+)
+
+// Ephemeral is an ephemeral message shown only to the user
+type Ephemeral struct {
+	ChannelID string
+	UserID    string
+	Text      string
+	Blocks    slack.Blocks
+}
+
+// Slack is in-process fake of slack web api which keeps channels, threads and messages in memory
+type Slack struct {
+	BotUserID string
+	Emoji     map[string]string
+
+	mutex      sync.Mutex
+	clock      int64
+	channels   map[string][]*slack.Message
+	profiles   map[string]*slack.UserProfile
+	admins     map[string]bool
+	views      []slack.ModalViewRequest
+	homeViews  map[string]slack.HomeTabViewRequest
+	ephemerals []Ephemeral
+	files      []slack.FileUploadParameters
+}
+
+// New creates an empty fake slack workspace
+func New(botUserID string) *Slack {
+	return &Slack{
+		BotUserID: botUserID,
+		Emoji:     map[string]string{"sushi": "https://emoji.test/sushi.png"},
+		channels:  map[string][]*slack.Message{},
+		profiles:  map[string]*slack.UserProfile{},
+		admins:    map[string]bool{},
+		homeViews: map[string]slack.HomeTabViewRequest{},
+	}
+}
+
+// AddUser registers user profile to the workspace
+func (s *Slack) AddUser(userID string, realName string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.profiles[userID] = &slack.UserProfile{
+		RealName: realName,
+		Image32:  fmt.Sprintf("https://avatar.test/%s.png", userID),
+	}
+}
+
+// SetAdmin makes the registered user an admin of the workspace
+func (s *Slack) SetAdmin(userID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.admins[userID] = true
+}
+
+// PostUserMessage posts message as a user and returns its timestamp, thread timestamp can be empty
+func (s *Slack) PostUserMessage(channelID string, userID string, text string, threadTimestamp string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.post(channelID, userID, text, slack.Blocks{}, threadTimestamp).Timestamp
+}
+
+// Message returns a copy of the message
+func (s *Slack) Message(channelID string, timestamp string) (slack.Message, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	message, err := s.find(channelID, timestamp)
+	if err != nil {
+		return slack.Message{}, err
+	}
+	return copyMessage(message), nil
+}
+
+// Messages returns copies of all messages in the channel ordered by timestamp
+func (s *Slack) Messages(channelID string) []slack.Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	messages := []slack.Message{}
+	for _, message := range s.channels[channelID] {
+		messages = append(messages, copyMessage(message))
+	}
+	return messages
+}
+
+// Views returns views opened so far
+func (s *Slack) Views() []slack.ModalViewRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]slack.ModalViewRequest{}, s.views...)
+}
+
+// Ephemerals returns ephemeral messages posted so far
+func (s *Slack) Ephemerals() []Ephemeral {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Ephemeral{}, s.ephemerals...)
+}
+
+// PostMessage posts message as the bot
+func (s *Slack) PostMessage(channelID string, options ...slack.MsgOption) (string, string, error) {
+	text, blocks, threadTimestamp, err := applyOptions(channelID, options)
+	if err != nil {
+		return "", "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if threadTimestamp != "" {
+		if _, err := s.find(channelID, threadTimestamp); err != nil {
+			return "", "", err
+		}
+	}
+	message := s.post(channelID, s.BotUserID, text, blocks, threadTimestamp)
+	return channelID, message.Timestamp, nil
+}
+
+// PostEphemeral records ephemeral message
+func (s *Slack) PostEphemeral(channelID, userID string, options ...slack.MsgOption) (string, error) {
+	text, blocks, _, err := applyOptions(channelID, options)
+	if err != nil {
+		return "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.ephemerals = append(s.ephemerals, Ephemeral{ChannelID: channelID, UserID: userID, Text: text, Blocks: blocks})
+	return s.nextTimestamp(), nil
+}
+
+// UpdateMessage replaces text and blocks of the message
+func (s *Slack) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	text, blocks, _, err := applyOptions(channelID, options)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	message, err := s.find(channelID, timestamp)
+	if err != nil {
+		return "", "", "", err
+	}
+	message.Text = text
+	message.Blocks = blocks
+	return channelID, timestamp, text, nil
+}
+
+// DeleteMessage deletes the message
+func (s *Slack) DeleteMessage(channelID, timestamp string) (string, string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	messages := s.channels[channelID]
+	for i, message := range messages {
+		if message.Timestamp == timestamp {
+			s.channels[channelID] = append(messages[:i], messages[i+1:]...)
+			return channelID, timestamp, nil
+		}
+	}
+	return "", "", ErrMessageNotFound
+}
+
+// OpenView records opened view
+func (s *Slack) OpenView(triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.views = append(s.views, view)
+	response := &slack.ViewResponse{}
+	response.ID = fmt.Sprintf("V%d", len(s.views))
+	response.CallbackID = view.CallbackID
+	response.PrivateMetadata = view.PrivateMetadata
+	return response, nil
+}
+
+// PublishView records the home tab view of the user
+func (s *Slack) PublishView(userID string, view slack.HomeTabViewRequest, hash string) (*slack.ViewResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.homeViews[userID] = view
+	response := &slack.ViewResponse{}
+	response.ID = "V" + userID
+	return response, nil
+}
+
+// HomeView returns the home tab view published to the user
+func (s *Slack) HomeView(userID string) (slack.HomeTabViewRequest, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	view, ok := s.homeViews[userID]
+	return view, ok
+}
+
+// Files returns files uploaded by the bot
+func (s *Slack) Files() []slack.FileUploadParameters {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]slack.FileUploadParameters{}, s.files...)
+}
+
+// OpenConversation returns direct message channel with the user
+func (s *Slack) OpenConversation(params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error) {
+	if len(params.Users) != 1 {
+		return nil, false, false, ErrChannelNotFound
+	}
+	channel := &slack.Channel{}
+	channel.ID = "D" + params.Users[0]
+	return channel, false, true, nil
+}
+
+// UploadFile keeps the uploaded file
+func (s *Slack) UploadFile(params slack.FileUploadParameters) (*slack.File, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.files = append(s.files, params)
+	return &slack.File{Name: params.Filename, Title: params.Title}, nil
+}
+
+// GetPermalink returns fake permalink of the message
+func (s *Slack) GetPermalink(params *slack.PermalinkParameters) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := s.find(params.Channel, params.Ts); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://fake.slack.test/archives/%s/p%s", params.Channel, strings.Replace(params.Ts, ".", "", 1)), nil
+}
+
+// GetUserProfile returns registered user profile
+func (s *Slack) GetUserProfile(params *slack.GetUserProfileParameters) (*slack.UserProfile, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	profile, ok := s.profiles[params.UserID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	copied := *profile
+	return &copied, nil
+}
+
+// GetUserInfo returns registered user with the profile
+func (s *Slack) GetUserInfo(userID string) (*slack.User, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	profile, ok := s.profiles[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &slack.User{ID: userID, RealName: profile.RealName, Profile: *profile, IsAdmin: s.admins[userID]}, nil
+}
+
+// GetConversationReplies returns the thread of the message, parent first
+func (s *Slack) GetConversationReplies(params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	message, err := s.find(params.ChannelID, params.Timestamp)
+	if err != nil {
+		return nil, false, "", err
+	}
+	threadTimestamp := message.ThreadTimestamp
+	if threadTimestamp == "" {
+		threadTimestamp = message.Timestamp
+	}
+
+	messages := []slack.Message{}
+	for _, message := range s.channels[params.ChannelID] {
+		if message.Timestamp == threadTimestamp || message.ThreadTimestamp == threadTimestamp {
+			messages = append(messages, copyMessage(message))
+		}
+	}
+	return messages, false, "", nil
+}
+
+// GetEmoji returns custom emoji of the workspace
+func (s *Slack) GetEmoji() (map[string]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	emoji := map[string]string{}
+	for name, url := range s.Emoji {
+		emoji[name] = url
+	}
+	return emoji, nil
+}
+
+// AddReaction adds reaction of the bot to the message
+func (s *Slack) AddReaction(name string, item slack.ItemRef) error {
+	return s.AddUserReaction(item.Channel, item.Timestamp, s.BotUserID, name)
+}
+
+// GetReactions returns reactions of the message
+func (s *Slack) GetReactions(item slack.ItemRef, params slack.GetReactionsParameters) ([]slack.ItemReaction, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	message, err := s.find(item.Channel, item.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	return copyMessage(message).Reactions, nil
+}
+
+// AddUserReaction adds reaction of the user to the message
+func (s *Slack) AddUserReaction(channelID string, timestamp string, userID string, name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	message, err := s.find(channelID, timestamp)
+	if err != nil {
+		return err
+	}
+	for i, reaction := range message.Reactions {
+		if reaction.Name != name {
+			continue
+		}
+		for _, reactedUserID := range reaction.Users {
+			if reactedUserID == userID {
+				return ErrAlreadyReacted
+			}
+		}
+		message.Reactions[i].Count++
+		message.Reactions[i].Users = append(message.Reactions[i].Users, userID)
+		return nil
+	}
+	message.Reactions = append(message.Reactions, slack.ItemReaction{Name: name, Count: 1, Users: []string{userID}})
+	return nil
+}
+
+// RemoveUserReaction removes reaction of the user from the message
+func (s *Slack) RemoveUserReaction(channelID string, timestamp string, userID string, name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	message, err := s.find(channelID, timestamp)
+	if err != nil {
+		return err
+	}
+	for i, reaction := range message.Reactions {
+		if reaction.Name != name {
+			continue
+		}
+		for j, reactedUserID := range reaction.Users {
+			if reactedUserID != userID {
+				continue
+			}
+			message.Reactions[i].Users = append(reaction.Users[:j], reaction.Users[j+1:]...)
+			message.Reactions[i].Count--
+			if message.Reactions[i].Count == 0 {
+				message.Reactions = append(message.Reactions[:i], message.Reactions[i+1:]...)
+			}
+			return nil
+		}
+	}
+	return ErrNoReaction
+}
+
+func (s *Slack) nextTimestamp() string {
+	s.clock++
+	return fmt.Sprintf("1600000000.%06d", s.clock)
+}
+
+func (s *Slack) post(channelID string, userID string, text string, blocks slack.Blocks, threadTimestamp string) *slack.Message {
+	message := &slack.Message{}
+	message.Type = "message"
+	message.Channel = channelID
+	message.User = userID
+	message.Text = text
+	message.Blocks = blocks
+	message.Timestamp = s.nextTimestamp()
+	if parent, err := s.find(channelID, threadTimestamp); err == nil {
+		if root, err := s.find(channelID, parent.ThreadTimestamp); err == nil {
+			parent = root
+		}
+		parent.ThreadTimestamp = parent.Timestamp
+		message.ThreadTimestamp = parent.Timestamp
+		message.ParentUserId = parent.User
+	}
+
+	s.channels[channelID] = append(s.channels[channelID], message)
+	sort.SliceStable(s.channels[channelID], func(i, j int) bool {
+		return s.channels[channelID][i].Timestamp < s.channels[channelID][j].Timestamp
+	})
+	return message
+}
+
+func (s *Slack) find(channelID string, timestamp string) (*slack.Message, error) {
+	messages, ok := s.channels[channelID]
+	if !ok {
+		return nil, ErrChannelNotFound
+	}
+	for _, message := range messages {
+		if message.Timestamp == timestamp {
+			return message, nil
+		}
+	}
+	return nil, ErrMessageNotFound
+}
+
+// applyOptions extracts text, blocks and thread timestamp from message options as slack would receive them
+func applyOptions(channelID string, options []slack.MsgOption) (string, slack.Blocks, string, error) {
+	_, values, err := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	if err != nil {
+		return "", slack.Blocks{}, "", err
+	}
+
+	blocks := slack.Blocks{}
+	if rawBlocks := values.Get("blocks"); rawBlocks != "" {
+		if err := json.Unmarshal([]byte(rawBlocks), &blocks); err != nil {
+			return "", slack.Blocks{}, "", err
+		}
+	}
+	return values.Get("text"), blocks, values.Get("thread_ts"), nil
+}
+
+// copyMessage deep copies message through json so that callers can not mutate the stored one
+func copyMessage(message *slack.Message) slack.Message {
+	copied := slack.Message{}
+	encoded, _ := json.Marshal(message)
+	json.Unmarshal(encoded, &copied)
+	return copied
+}
@@ -2,13 +2,27 @@ package ids
 
 // Action IDs
 const (
-	SelectMenuByUser = "select_menu_by_user"
-	SubmitMenuInput  = "submit_menu_input"
-	SubmitMenuPeople = "submit_menu_people"
-	AddMenu          = "add_menu"
-	DeleteMenu       = "delete_menu"
-	OrderForOther    = "order_for_other"
-	TerminateMenu    = "terminate_menu"
+	SelectMenuByUser   = "select_menu_by_user"
+	SubmitMenuInput    = "submit_menu_input"
+	SubmitMenuPeople   = "submit_menu_people"
+	AddMenu            = "add_menu"
+	DeleteMenu         = "delete_menu"
+	OrderForOther      = "order_for_other"
+	TerminateMenu      = "terminate_menu"
+	OpenStartBoard     = "open_start_board"
+	StartBoardChannel  = "start_board_channel"
+	StartBoardTitle    = "start_board_title"
+	StartBoardDeadline = "start_board_deadline"
+	StartBoardMenus    = "start_board_menus"
+	RestoreMenu        = "restore_menu"
+	UndoBoardChange    = "undo_board_change"
+	AuditUser          = "audit_user"
+	AuditMenu          = "audit_menu"
+	ExportAuditLog     = "export_audit_log"
+	OrderMode          = "order_mode"
+	OrderGuests        = "order_guests"
+	MenuTags           = "menu_tags"
+	DietRestrictions   = "diet_restrictions"
 )
 
 // Block IDs
@@ -19,11 +33,36 @@ const (
 	MenuButtonsBlock            = "menu_buttons_block"
 	MenuSelectContextBlock      = "menu_select_context_block/"
 	QuoteBlock                  = "quote_block"
+	BoardContinuationBlock      = "board_continuation_block/"
+	MenuHeaderBlock             = "menu_header_block/"
+	DeadlineBlock               = "deadline_block/"
+	ReactionModeBlock           = "reaction_mode_block"
+	MenuReactionBlock           = "menu_reaction_block/"
+	MenuBlock                   = "menu_block/"
+	CohostBlock                 = "cohost_block/"
+	GuestBlock                  = "guest_block/"
+	OrderGuestsBlock            = "order_guests_block"
+	MenuTagsBlock               = "menu_tags_block"
+	StartBoardChannelBlock      = "start_board_channel_block"
+	StartBoardTitleBlock        = "start_board_title_block"
+	StartBoardDeadlineBlock     = "start_board_deadline_block"
+	StartBoardMenusBlock        = "start_board_menus_block"
+	AuditUserBlock              = "audit_user_block"
+	AuditMenuBlock              = "audit_menu_block"
+	OrderModeBlock              = "order_mode_block"
+	BusyBlock                   = "busy_block"
 )
 
 // Callback IDs
 const (
-	SubmitMenuCallback          = "submit_menu_callback"
-	SubmitDeleteMenuCallback    = "submit_delete_menu_callback"
-	SubmitOrderForOtherCallback = "submit_order_for_other_callback"
+	SubmitMenuCallback                 = "submit_menu_callback"
+	SubmitDeleteMenuCallback           = "submit_delete_menu_callback"
+	SubmitOrderForOtherCallback        = "submit_order_for_other_callback"
+	SubmitStartBoardCallback           = "submit_start_board_callback"
+	SubmitDeleteMenuConfirmCallback    = "submit_delete_menu_confirm_callback"
+	StartBoardShortcut                 = "start_board_shortcut"
+	StartBoardHereShortcut             = "start_board_here"
+	SubmitAuditLogCallback             = "submit_audit_log_callback"
+	AuditLogShortcut                   = "board_audit_log"
+	SubmitOrderForOtherConfirmCallback = "submit_order_for_other_confirm_callback"
 )
@@ -8,40 +8,122 @@ import (
 
 	"math/rand"
 	"time"
+	// Timezones are loaded from the binary since the scratch image has no zoneinfo
+	_ "time/tzdata"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
 )
 
+const numWorkers = 8
+const jobQueueSize = 256
+
+// Slack retries events for several minutes when they are not acknowledged
+const idempotencyTTL = 10 * time.Minute
+
+// Profiles are invalidated by user_change events, the TTL only bounds staleness of missed ones
+const profileCacheTTL = time.Hour
+
+// The users of the bot mostly order in Korea
+const defaultTimezone = "Asia/Seoul"
+
 func main() {
 	logger := log.New(os.Stdout, "", log.LstdFlags)
 
 	// "Bot User OAuth Access Token" which starts with "xoxb-"
 	slackBotToken := os.Getenv("SLACK_BOT_USER_TOKEN")
 	signingSecret := os.Getenv("SLACK_SIGNING_SECRET")
+	// "http" to serve public endpoints or "socket" to connect with socket mode
+	transport := os.Getenv("SLACK_TRANSPORT")
+	// "App-Level Token" which starts with "xapp-", required for socket mode
+	slackAppToken := os.Getenv("SLACK_APP_TOKEN")
+	// OAuth app credentials to install the bot to multiple workspaces
+	clientID := os.Getenv("SLACK_CLIENT_ID")
+	clientSecret := os.Getenv("SLACK_CLIENT_SECRET")
+	redirectURL := os.Getenv("SLACK_REDIRECT_URL")
+	// File to keep bot tokens of installed workspaces
+	tokenStorePath := os.Getenv("SLACK_TOKEN_STORE_PATH")
+	// File to keep board history, menu templates and channel configs
+	dataPath := os.Getenv("WAITER_DATA_PATH")
+	// File to append the audit log of board changes as JSON Lines
+	auditPath := os.Getenv("WAITER_AUDIT_PATH")
+	// Timezone which deadlines like "마감 12:30" are written in and times are shown in
+	timezone := os.Getenv("WAITER_TIMEZONE")
+	if timezone == "" {
+		timezone = defaultTimezone
+	}
+
+	rand.Seed(time.Now().Unix())
 
-	client, botUserID, err := service.AuthorizeSlack(slackBotToken)
+	tokenStore, err := service.NewTokenStore(tokenStorePath)
 	if err != nil {
-		logger.Fatal("[FATAL] INVALID TOKEN ERROR")
+		logger.Fatal("[FATAL] INVALID TOKEN STORE")
 	}
-
-	emojiManager := &service.EmojiManager{
-		Client: client,
+	store, err := service.NewStore(dataPath)
+	if err != nil {
+		logger.Fatal("[FATAL] INVALID DATA STORE")
 	}
-	if err := emojiManager.UpdateEmojiList(); err != nil {
-		logger.Fatal("[FATAL] INVALID EMOTION PERMISSION")
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		logger.Fatal("[FATAL] INVALID TIMEZONE")
+	}
+	workspaces := &service.WorkspaceManager{
+		Store: tokenStore,
+		NewClient: func(botToken string) service.SlackAPI {
+			return service.NewRetryClient(slack.New(botToken), logger)
+		},
+		ProfileCacheTTL: profileCacheTTL,
 	}
 
-	rand.Seed(time.Now().Unix())
+	if slackBotToken != "" {
+		client, botUserID, teamID, err := service.AuthorizeSlack(slackBotToken)
+		if err != nil {
+			logger.Fatal("[FATAL] INVALID TOKEN ERROR")
+		}
+
+		workspace := service.NewWorkspace(teamID, service.NewRetryClient(client, logger), botUserID, profileCacheTTL)
+		if err := workspace.EmojiManager.UpdateEmojiList(); err != nil {
+			logger.Fatal("[FATAL] INVALID EMOTION PERMISSION")
+		}
+		workspaces.Add(workspace)
+	}
 
 	handler := &service.Handler{
-		Client:        client,
 		SigningSecret: signingSecret,
-		BotUserID:     botUserID,
-		EmojiManager:  emojiManager,
+		Dispatcher:    service.NewDispatcher(logger, numWorkers, jobQueueSize),
+		Idempotency:   service.NewIdempotencyCache(idempotencyTTL),
+		Workspaces:    workspaces,
+		Store:         store,
+		Audit:         service.NewAuditLog(auditPath),
+		Commands:      service.NewCommandRouter(),
+		Location:      location,
 		Logger:        logger,
 	}
 
+	handler.RestoreDeadlines()
+
+	if transport == "socket" {
+		logger.Println("[INFO] Running with socket mode")
+		logger.Fatal(handler.RunSocketMode(socketmode.New(slack.New(slackBotToken, slack.OptionAppLevelToken(slackAppToken)))))
+	}
+
+	if clientID != "" {
+		installer := &service.Installer{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Workspaces:   workspaces,
+			Logger:       logger,
+		}
+		http.HandleFunc("/slack/install", installer.HandleInstall)
+		http.HandleFunc("/slack/oauth_redirect", installer.HandleOAuthRedirect)
+	}
+
 	http.HandleFunc("/status", handler.HandleStatus)
-	http.HandleFunc("/events", handler.HandleEvent)
-	http.HandleFunc("/actions", handler.HandleAction)
+	http.HandleFunc("/events", handler.VerifyRequest(handler.HandleEvent))
+	http.HandleFunc("/actions", handler.VerifyRequest(handler.HandleAction))
+	http.HandleFunc("/options", handler.VerifyRequest(handler.HandleOptions))
+	http.HandleFunc("/commands", handler.VerifyRequest(handler.HandleCommand))
 
 	logger.Println("[INFO] Server listening")
 	http.ListenAndServe(":8080", nil)
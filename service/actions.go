@@ -6,18 +6,33 @@ import (
 	"slack-waiter-bot/ids"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/slack-go/slack"
 )
 
 var messageUpdateMutex = &sync.Mutex{}
 
+// Modes of order for other
+const (
+	OrderModeAdd    = "add"
+	OrderModeRemove = "remove"
+	OrderModeToggle = "toggle"
+)
+
+var orderModeNames = map[string]string{
+	OrderModeAdd:    "선택하기",
+	OrderModeRemove: "선택 취소하기",
+	OrderModeToggle: "선택/취소 뒤집기",
+}
+
 // AddMenu handles when user clicks addmenu button
-func AddMenu(handler *Handler, payload *slack.InteractionCallback) {
+func AddMenu(handler *Handler, payload *slack.InteractionCallback) error {
 	// Menu Input Block
 	menuNameText := slack.NewTextBlockObject("plain_text", "메뉴를 골라달라옹", false, false)
 	menuNamePlaceholder := slack.NewTextBlockObject("plain_text", "ex) 회전초밥 32pc", false, false)
 	menuNameElement := slack.NewPlainTextInputBlockElement(menuNamePlaceholder, ids.SubmitMenuInput)
+	menuNameElement.MaxLength = maxMenuNameLength
 	menuName := slack.NewInputBlock(ids.SubmitMenuInputBlock, menuNameText, menuNameElement)
 
 	// User Select Block
@@ -26,6 +41,12 @@ func AddMenu(handler *Handler, payload *slack.InteractionCallback) {
 	multiUserSelect.InitialUsers = []string{payload.User.ID}
 	userSelect := slack.NewInputBlock(ids.SubmitMenuSelectPeopleBlock, userSelectText, multiUserSelect)
 
+	// Menu Tags Block
+	menuTagsText := slack.NewTextBlockObject("plain_text", "들어간 재료가 있으면 골라달라옹", false, false)
+	menuTagsElement := slack.NewCheckboxGroupsBlockElement(ids.MenuTags, NewMenuTagOptions()...)
+	menuTags := slack.NewInputBlock(ids.MenuTagsBlock, menuTagsText, menuTagsElement)
+	menuTags.Optional = true
+
 	var modalRequest slack.ModalViewRequest
 	modalRequest.Type = slack.ViewType("modal")
 	modalRequest.Title = slack.NewTextBlockObject("plain_text", "메뉴 추가", false, false)
@@ -35,47 +56,62 @@ func AddMenu(handler *Handler, payload *slack.InteractionCallback) {
 	modalRequest.PrivateMetadata = WriteCallbackMetadata(payload.Channel.ID, payload.Message.Timestamp)
 	modalRequest.Blocks = slack.Blocks{
 		BlockSet: []slack.Block{
-			menuName, userSelect,
+			menuName, userSelect, menuTags,
 		},
 	}
 
-	handler.Client.OpenView(payload.TriggerID, modalRequest)
+	_, err := handler.Client.OpenView(payload.TriggerID, modalRequest)
+	return err
 }
 
 // DeleteMenu handles when user clicks delete menu button
-func DeleteMenu(handler *Handler, payload *slack.InteractionCallback) {
-	message := GetMessageFromTimeStamp(handler.Client, payload.Channel.ID, payload.Message.Timestamp)
-	menuBoard := ParseMenuBlocks(message.Blocks.BlockSet)
+func DeleteMenu(handler *Handler, payload *slack.InteractionCallback) error {
+	menuBoard, err := LoadMenuBoard(handler.Client, payload.Channel.ID, payload.Message.Timestamp)
+	if err != nil {
+		return err
+	}
+	if handler.Store.ChannelConfig(handler.TeamID, menuBoard.ChannelID).DeletePolicy == DeletePolicyHost {
+		canManage, err := CanManageBoard(handler, menuBoard, payload.User.ID)
+		if err != nil {
+			return err
+		}
+		if !canManage {
+			return ReplyEphemeral(handler, menuBoard, payload.User.ID, "이 채널에서는 메뉴판을 연 사람만 메뉴를 지울 수 있다옹")
+		}
+	}
 
 	// Menu Input Block
-	menuListText := slack.NewTextBlockObject("plain_text", "⚠️ 메뉴와 선택한 사람들이 모두 사라지니 조심해달라옹 ⚠️", false, false)
-	menuListElement := slack.NewRadioButtonsBlockElement(ids.SubmitMenuInput, menuBoard.ToOptionBlockObjects()...)
+	menuListText := slack.NewTextBlockObject("plain_text", "지울 메뉴를 골라달라옹", false, false)
+	menuListElement := NewMenuExternalSelectElement()
 	menuList := slack.NewInputBlock(ids.SubmitMenuDeleteBlock, menuListText, menuListElement)
 
 	var modalRequest slack.ModalViewRequest
 	modalRequest.Type = slack.ViewType("modal")
 	modalRequest.Title = slack.NewTextBlockObject("plain_text", "메뉴 삭제", false, false)
 	modalRequest.Close = slack.NewTextBlockObject("plain_text", "Close", false, false)
-	modalRequest.Submit = slack.NewTextBlockObject("plain_text", "Submit", false, false)
+	modalRequest.Submit = slack.NewTextBlockObject("plain_text", "Next", false, false)
 	modalRequest.CallbackID = ids.SubmitDeleteMenuCallback
-	modalRequest.PrivateMetadata = WriteCallbackMetadata(payload.Channel.ID, payload.Message.Timestamp)
+	modalRequest.PrivateMetadata = WriteCallbackMetadata(menuBoard.ChannelID, menuBoard.Timestamp)
 	modalRequest.Blocks = slack.Blocks{
 		BlockSet: []slack.Block{
 			menuList,
 		},
 	}
 
-	handler.Client.OpenView(payload.TriggerID, modalRequest)
+	_, err = handler.Client.OpenView(payload.TriggerID, modalRequest)
+	return err
 }
 
 // OrderForOther handles when user clicks order for other button
-func OrderForOther(handler *Handler, payload *slack.InteractionCallback) {
-	message := GetMessageFromTimeStamp(handler.Client, payload.Channel.ID, payload.Message.Timestamp)
-	menuBoard := ParseMenuBlocks(message.Blocks.BlockSet)
+func OrderForOther(handler *Handler, payload *slack.InteractionCallback) error {
+	menuBoard, err := LoadMenuBoard(handler.Client, payload.Channel.ID, payload.Message.Timestamp)
+	if err != nil {
+		return err
+	}
 
 	// Menu Select Block
 	menuSelectText := slack.NewTextBlockObject("plain_text", "메뉴를 고르라옹", false, false)
-	menuSelectElement := slack.NewOptionsSelectBlockElement("static_select", nil, ids.SubmitMenuInput, menuBoard.ToOptionBlockObjects()...)
+	menuSelectElement := NewMenuExternalSelectElement()
 	menuSelect := slack.NewInputBlock(ids.SubmitMenuInputBlock, menuSelectText, menuSelectElement)
 
 	// User Select Block
@@ -83,127 +119,613 @@ func OrderForOther(handler *Handler, payload *slack.InteractionCallback) {
 	multiUserSelect := slack.NewOptionsMultiSelectBlockElement("multi_users_select", nil, ids.SubmitMenuPeople)
 	userSelect := slack.NewInputBlock(ids.SubmitMenuSelectPeopleBlock, userSelectText, multiUserSelect)
 
+	// Mode Select Block
+	modeOptions := []*slack.OptionBlockObject{}
+	for _, mode := range []string{OrderModeAdd, OrderModeRemove, OrderModeToggle} {
+		modeOptions = append(modeOptions, slack.NewOptionBlockObject(mode, slack.NewTextBlockObject("plain_text", orderModeNames[mode], false, false), nil))
+	}
+	modeSelectText := slack.NewTextBlockObject("plain_text", "어떻게 바꿀지 고르라옹", false, false)
+	modeSelectElement := slack.NewRadioButtonsBlockElement(ids.OrderMode, modeOptions...)
+	modeSelectElement.InitialOption = modeOptions[0]
+	modeSelect := slack.NewInputBlock(ids.OrderModeBlock, modeSelectText, modeSelectElement)
+
 	var modalRequest slack.ModalViewRequest
 	modalRequest.Type = slack.ViewType("modal")
 	modalRequest.Title = slack.NewTextBlockObject("plain_text", "메뉴 선택/취소 대신해주기", false, false)
 	modalRequest.Close = slack.NewTextBlockObject("plain_text", "Close", false, false)
-	modalRequest.Submit = slack.NewTextBlockObject("plain_text", "Submit", false, false)
+	modalRequest.Submit = slack.NewTextBlockObject("plain_text", "Next", false, false)
 	modalRequest.CallbackID = ids.SubmitOrderForOtherCallback
-	modalRequest.PrivateMetadata = WriteCallbackMetadata(payload.Channel.ID, payload.Message.Timestamp)
+	modalRequest.PrivateMetadata = WriteCallbackMetadata(menuBoard.ChannelID, menuBoard.Timestamp)
 	modalRequest.Blocks = slack.Blocks{
 		BlockSet: []slack.Block{
-			menuSelect, userSelect,
+			menuSelect, userSelect, modeSelect,
 		},
 	}
 
-	handler.Client.OpenView(payload.TriggerID, modalRequest)
+	// Guest Select Block, where either people or guests are to be chosen
+	if len(menuBoard.Guests) > 0 {
+		guestOptions := []*slack.OptionBlockObject{}
+		for _, guest := range menuBoard.Guests {
+			guestOptions = append(guestOptions, slack.NewOptionBlockObject(guest.Name, slack.NewTextBlockObject("plain_text", guest.ChooserName(), false, false), nil))
+		}
+		guestSelectText := slack.NewTextBlockObject("plain_text", "손님도 고르라옹", false, false)
+		guestSelectElement := slack.NewOptionsMultiSelectBlockElement(slack.MultiOptTypeStatic, nil, ids.OrderGuests, guestOptions...)
+		guestSelect := slack.NewInputBlock(ids.OrderGuestsBlock, guestSelectText, guestSelectElement)
+		guestSelect.Optional = true
+		userSelect.Optional = true
+		modalRequest.Blocks.BlockSet = []slack.Block{menuSelect, userSelect, guestSelect, modeSelect}
+	}
+
+	_, err = handler.Client.OpenView(payload.TriggerID, modalRequest)
+	return err
 }
 
 // TerminateMenu handles when user clicks terminate button
-func TerminateMenu(handler *Handler, payload *slack.InteractionCallback) {
+func TerminateMenu(handler *Handler, payload *slack.InteractionCallback) error {
 	messageUpdateMutex.Lock()
 	defer messageUpdateMutex.Unlock()
 
-	message := GetMessageFromTimeStamp(handler.Client, payload.Channel.ID, payload.Message.Timestamp)
-	menuBoard := ParseMenuBlocks(message.Blocks.BlockSet)
+	menuBoard, err := LoadMenuBoard(handler.Client, payload.Channel.ID, payload.Message.Timestamp)
+	if err != nil {
+		return err
+	}
 
-	if message.ParentUserId != payload.User.ID {
-		return
+	canManage, err := CanManageBoard(handler, menuBoard, payload.User.ID)
+	if err != nil {
+		return err
+	}
+	if !canManage {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, "메뉴판은 연 사람이나 같이 여는 사람만 마감할 수 있다옹")
 	}
+	return CloseMenuBoard(handler, menuBoard, payload.User.ID)
+}
 
-	blocks := []slack.Block{}
-	for _, curBlock := range message.Blocks.BlockSet {
-		switch curBlock.BlockType() {
-		case slack.MBTAction:
-		case slack.MBTSection:
-			curBlock.(*slack.SectionBlock).Accessory = nil
-			blocks = append(blocks, curBlock)
-		default:
-			blocks = append(blocks, curBlock)
+// CloseMenuBoard replaces the buttons of the board with the summary and records it, the caller should hold messageUpdateMutex
+// userID is who terminates the board, empty when the bot closes it at the deadline
+func CloseMenuBoard(handler *Handler, menuBoard *MenuBoard, userID string) error {
+	if menuBoard.ReactionMode {
+		if err := SyncReactions(handler, menuBoard); err != nil {
+			return err
 		}
 	}
 
 	summary := ""
 	for _, menu := range menuBoard.Menus {
+		menu.MenuSelectBlock.Accessory = nil
 		choosers := menu.GetChoosers()
 		summary += fmt.Sprintf("*%s*\n>", menu.MenuName)
 		summary += "`" + strings.Join(choosers, "` `") + "`\n"
 	}
-	blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", summary, false, false), nil, nil))
+	summary += guestSummary(menuBoard)
+	summary += dietSummary(handler, menuBoard)
 
-	quote := Quotes[rand.Intn(len(Quotes))]
-	blocks = append(blocks, slack.NewDividerBlock(), slack.NewContextBlock(ids.QuoteBlock, slack.NewTextBlockObject("plain_text", quote, false, false)))
+	tailBlocks := []slack.Block{}
+	for _, curBlock := range menuBoard.TailBlocks {
+		if curBlock.BlockType() != slack.MBTAction {
+			tailBlocks = append(tailBlocks, curBlock)
+		}
+	}
+	tailBlocks = append(tailBlocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", summary, false, false), nil, nil))
+
+	if !handler.Store.ChannelConfig(handler.TeamID, menuBoard.ChannelID).NoQuote {
+		quote := Quotes[rand.Intn(len(Quotes))]
+		tailBlocks = append(tailBlocks, slack.NewDividerBlock(), slack.NewContextBlock(ids.QuoteBlock, slack.NewTextBlockObject("plain_text", quote, false, false)))
+	}
+	menuBoard.TailBlocks = tailBlocks
 
-	handler.Client.UpdateMessage(payload.Channel.ID, message.Timestamp, slack.MsgOptionBlocks(blocks...))
+	if err := SaveMenuBoard(handler.Client, menuBoard); err != nil {
+		return err
+	}
+	if err := handler.Store.RecordBoard(NewBoardRecord(handler.TeamID, menuBoard)); err != nil {
+		return err
+	}
+	if err := RecordTerminate(handler, menuBoard, userID); err != nil {
+		return err
+	}
+	if err := AuditBoard(handler, menuBoard, userID, AuditTerminate, "", ""); err != nil {
+		return err
+	}
+	return handler.Store.RemoveActiveBoard(handler.TeamID, menuBoard.ChannelID, menuBoard.Timestamp)
+}
+
+// guestSummary returns the menus of the guests grouped by the sponsors who pay for them
+func guestSummary(menuBoard *MenuBoard) string {
+	summary := ""
+	for _, sponsorUserID := range menuBoard.GuestSponsors() {
+		lines := ""
+		for _, guest := range menuBoard.Guests {
+			if guest.SponsorUserID != sponsorUserID {
+				continue
+			}
+			menuNames := []string{}
+			for _, menu := range menuBoard.Menus {
+				if menuBoard.HasChosen(menu.MenuName, guest.ChooserName()) {
+					menuNames = append(menuNames, menu.MenuName)
+				}
+			}
+			if len(menuNames) > 0 {
+				lines += fmt.Sprintf(">%s: %s\n", guest.ChooserName(), strings.Join(menuNames, ", "))
+			}
+		}
+		if lines != "" {
+			summary += fmt.Sprintf("💸 *<@%s>님이 내는 손님 몫*\n%s", sponsorUserID, lines)
+		}
+	}
+	return summary
 }
 
 // SelectMenuByUser handles when user select a menu
-func SelectMenuByUser(handler *Handler, payload *slack.InteractionCallback, selectedMenuName string) {
-	profile, _ := handler.Client.GetUserProfile(&slack.GetUserProfileParameters{UserID: payload.User.ID})
+func SelectMenuByUser(handler *Handler, payload *slack.InteractionCallback, selectedMenuName string) error {
+	profile, err := handler.Profiles.GetProfile(payload.User.ID)
+	if err != nil {
+		return err
+	}
 
 	messageUpdateMutex.Lock()
 	defer messageUpdateMutex.Unlock()
 
-	message := GetMessageFromTimeStamp(handler.Client, payload.Channel.ID, payload.Message.Timestamp)
-	menuBoard := ParseMenuBlocks(message.Blocks.BlockSet)
+	menuBoard, err := LoadMenuBoard(handler.Client, payload.Channel.ID, payload.Message.Timestamp)
+	if err != nil {
+		return err
+	}
+	if menuBoard.IsTerminated() {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, "이미 마감된 메뉴판이다옹")
+	}
+	// The deadline job may still be waiting in the queue, or dropped when it was full, so late selections close the board instead
+	if !menuBoard.Deadline.IsZero() && time.Now().After(menuBoard.Deadline) {
+		return CloseMenuBoard(handler, menuBoard, "")
+	}
+	// The button may belong to a menu deleted after the message was rendered
+	if _, ok := menuBoard.MenuNameIndexMap[selectedMenuName]; !ok {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, "이미 지워진 메뉴다옹")
+	}
 	menuBoard.ToggleMenuByUser(profile, selectedMenuName)
-	handler.Client.UpdateMessage(payload.Channel.ID, message.Timestamp, slack.MsgOptionBlocks(menuBoard.ToBlocks()...))
+	if err := SaveOpenBoard(handler, menuBoard); err != nil {
+		return err
+	}
+	if err := RecordToggle(handler, menuBoard, payload.User.ID, selectedMenuName, []*slack.UserProfile{profile}); err != nil {
+		return err
+	}
+	if err := AuditToggles(handler, menuBoard, payload.User.ID, selectedMenuName, []string{profile.RealName}, ""); err != nil {
+		return err
+	}
+	if !menuBoard.HasChosen(selectedMenuName, profile.RealName) {
+		return nil
+	}
+	return WarnDietConflict(handler, menuBoard, payload.User.ID, selectedMenuName)
 }
 
 // SubmitMenuAdd handles when user submit menu add view
-func SubmitMenuAdd(handler *Handler, payload *slack.InteractionCallback) {
+func SubmitMenuAdd(handler *Handler, payload *slack.InteractionCallback) error {
 	channel, originalPostTimeStamp := ParseCallbackMetadata(payload.View.PrivateMetadata)
+	menuName := strings.TrimSpace(payload.View.State.Values[ids.SubmitMenuInputBlock][ids.SubmitMenuInput].Value)
+	selectedUsers := payload.View.State.Values[ids.SubmitMenuSelectPeopleBlock][ids.SubmitMenuPeople].SelectedUsers
+	profiles, err := handler.Profiles.GetProfiles(selectedUsers)
+	if err != nil {
+		return err
+	}
 
 	messageUpdateMutex.Lock()
 	defer messageUpdateMutex.Unlock()
 
-	message := GetMessageFromTimeStamp(handler.Client, channel, originalPostTimeStamp)
-	menuName := payload.View.State.Values[ids.SubmitMenuInputBlock][ids.SubmitMenuInput].Value
-	menuBoard := ParseMenuBlocks(message.Blocks.BlockSet)
-	emoji, _ := handler.EmojiManager.GetRandomEmoji()
-	menuBoard.AddMenu(menuName, emoji)
+	menuBoard, err := LoadMenuBoard(handler.Client, channel, originalPostTimeStamp)
+	if err != nil {
+		return err
+	}
+	// The board may have changed since the view was validated
+	tags := MenuAddTags(payload)
+	if errorMessage := menuBoard.ValidateMenuAdd(menuName, payload.User.ID, tags); errorMessage != "" {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, fmt.Sprintf("%s: %s", menuName, errorMessage))
+	}
+	menuBoard.AddMenu(menuName, PickMenuEmoji(handler, menuBoard), payload.User.ID)
+	menuBoard.SetMenuTags(menuName, tags)
 
 	// Select default selected users
-	selectedUsers := payload.View.State.Values[ids.SubmitMenuSelectPeopleBlock][ids.SubmitMenuPeople].SelectedUsers
-	for _, user := range selectedUsers {
-		profile, _ := handler.Client.GetUserProfile(&slack.GetUserProfileParameters{UserID: user})
+	for _, profile := range profiles {
 		menuBoard.ToggleMenuByUser(profile, menuName)
 	}
-	handler.Client.UpdateMessage(channel, originalPostTimeStamp, slack.MsgOptionBlocks(menuBoard.ToBlocks()...))
+	if err := SaveOpenBoard(handler, menuBoard); err != nil {
+		return err
+	}
+	if err := RecordAdd(handler, menuBoard, payload.User.ID, []string{menuName}); err != nil {
+		return err
+	}
+	if err := AuditBoard(handler, menuBoard, payload.User.ID, AuditAdd, menuName, ""); err != nil {
+		return err
+	}
+	if err := AuditToggles(handler, menuBoard, payload.User.ID, menuName, profileNames(profiles), ""); err != nil {
+		return err
+	}
+	for i, profile := range profiles {
+		if menuBoard.HasChosen(menuName, profile.RealName) {
+			if err := WarnDietConflict(handler, menuBoard, selectedUsers[i], menuName); err != nil {
+				return err
+			}
+		}
+	}
+	return AddMenuReactions(handler, menuBoard, []string{menuName})
 }
 
-// SubmitOrderForOther handles when user submit order for other view
-func SubmitOrderForOther(handler *Handler, payload *slack.InteractionCallback) {
+// MenuAddTags returns the tags checked in menu add view
+func MenuAddTags(payload *slack.InteractionCallback) []string {
+	tags := []string{}
+	for _, option := range payload.View.State.Values[ids.MenuTagsBlock][ids.MenuTags].SelectedOptions {
+		tags = append(tags, option.Value)
+	}
+	return tags
+}
+
+// PreviewOrderForOther updates order for other view into the confirm step showing the choice of each user after the change
+func PreviewOrderForOther(handler *Handler, payload *slack.InteractionCallback) *slack.ViewSubmissionResponse {
 	channel, originalPostTimeStamp := ParseCallbackMetadata(payload.View.PrivateMetadata)
+	menuName := payload.View.State.Values[ids.SubmitMenuInputBlock][ids.SubmitMenuInput].SelectedOption.Value
+	selectedUsers := payload.View.State.Values[ids.SubmitMenuSelectPeopleBlock][ids.SubmitMenuPeople].SelectedUsers
+	mode := payload.View.State.Values[ids.OrderModeBlock][ids.OrderMode].SelectedOption.Value
+	if _, ok := orderModeNames[mode]; !ok {
+		mode = OrderModeAdd
+	}
+
+	menuBoard, err := LoadMenuBoard(handler.Client, channel, originalPostTimeStamp)
+	if err != nil {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{ids.SubmitMenuInputBlock: "메뉴판을 찾을 수 없다옹"})
+	}
+	if menuBoard.IsTerminated() {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{ids.SubmitMenuInputBlock: "이미 마감된 메뉴판이다옹"})
+	}
+	if _, ok := menuBoard.MenuNameIndexMap[menuName]; !ok {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{ids.SubmitMenuInputBlock: "메뉴판에 없는 메뉴다옹"})
+	}
+	guestNames := []string{}
+	for _, option := range payload.View.State.Values[ids.OrderGuestsBlock][ids.OrderGuests].SelectedOptions {
+		guestNames = append(guestNames, option.Value)
+	}
+	if len(selectedUsers) == 0 && len(guestNames) == 0 {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{ids.SubmitMenuSelectPeopleBlock: "사람이나 손님을 골라달라옹"})
+	}
+	profiles, err := handler.Profiles.GetProfiles(selectedUsers)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to get profiles: %v\n", err)
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{ids.SubmitMenuSelectPeopleBlock: "사람들을 찾지 못했다옹"})
+	}
+	profiles = append(profiles, guestProfiles(menuBoard, guestNames)...)
+
+	text := fmt.Sprintf("*%s* %s\n", menuName, orderModeNames[mode])
+	for _, profile := range profiles {
+		before, after := "안 고름", "안 고름"
+		if menuBoard.HasChosen(menuName, profile.RealName) {
+			before = "고름"
+		}
+		if planOrderChoice(menuBoard, menuName, mode, profile) {
+			after = "고름"
+		}
+		if before == after {
+			text += fmt.Sprintf("• %s: %s (그대로)\n", profile.RealName, after)
+		} else {
+			text += fmt.Sprintf("• %s: %s → *%s*\n", profile.RealName, before, after)
+		}
+	}
+
+	var modalRequest slack.ModalViewRequest
+	modalRequest.Type = slack.ViewType("modal")
+	modalRequest.Title = slack.NewTextBlockObject("plain_text", "메뉴 선택/취소 대신해주기", false, false)
+	modalRequest.Close = slack.NewTextBlockObject("plain_text", "Cancel", false, false)
+	modalRequest.Submit = slack.NewTextBlockObject("plain_text", "Apply", false, false)
+	modalRequest.CallbackID = ids.SubmitOrderForOtherConfirmCallback
+	modalRequest.PrivateMetadata = WriteOrderMetadata(menuBoard.ChannelID, menuBoard.Timestamp, mode, selectedUsers, guestNames, menuName)
+	modalRequest.Blocks = slack.Blocks{
+		BlockSet: []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+		},
+	}
+	return slack.NewUpdateViewSubmissionResponse(&modalRequest)
+}
+
+// guestProfiles returns profiles of the guests of the board with the names, skipping unknown ones
+func guestProfiles(menuBoard *MenuBoard, guestNames []string) []*slack.UserProfile {
+	profiles := []*slack.UserProfile{}
+	for _, name := range guestNames {
+		if guest, ok := menuBoard.FindGuest(name); ok {
+			profiles = append(profiles, guest.Profile())
+		}
+	}
+	return profiles
+}
+
+// planOrderChoice returns whether the user has chosen the menu after the change of the mode
+func planOrderChoice(menuBoard *MenuBoard, menuName string, mode string, profile *slack.UserProfile) bool {
+	switch mode {
+	case OrderModeAdd:
+		return true
+	case OrderModeRemove:
+		return false
+	}
+	return !menuBoard.HasChosen(menuName, profile.RealName)
+}
+
+// SubmitOrderForOther handles when user confirms order for other view, reporting what is changed to the user
+func SubmitOrderForOther(handler *Handler, payload *slack.InteractionCallback) error {
+	channel, originalPostTimeStamp, mode, selectedUsers, guestNames, menuName := ParseOrderMetadata(payload.View.PrivateMetadata)
+	profiles, err := handler.Profiles.GetProfiles(selectedUsers)
+	if err != nil {
+		return err
+	}
 
 	messageUpdateMutex.Lock()
 	defer messageUpdateMutex.Unlock()
 
-	message := GetMessageFromTimeStamp(handler.Client, channel, originalPostTimeStamp)
-	menuBoard := ParseMenuBlocks(message.Blocks.BlockSet)
-	menuName := payload.View.State.Values[ids.SubmitMenuInputBlock][ids.SubmitMenuInput].SelectedOption.Value
-	selectedUsers := payload.View.State.Values[ids.SubmitMenuSelectPeopleBlock][ids.SubmitMenuPeople].SelectedUsers
-
-	// Select selected users
-	for _, user := range selectedUsers {
-		profile, _ := handler.Client.GetUserProfile(&slack.GetUserProfileParameters{UserID: user})
+	menuBoard, err := LoadMenuBoard(handler.Client, channel, originalPostTimeStamp)
+	if err != nil {
+		return err
+	}
+	if menuBoard.IsTerminated() {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, "이미 마감된 메뉴판이다옹")
+	}
+	if _, ok := menuBoard.MenuNameIndexMap[menuName]; !ok {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, fmt.Sprintf("*%s*는 메뉴판에서 지워졌다옹", menuName))
+	}
+	profiles = append(profiles, guestProfiles(menuBoard, guestNames)...)
+
+	// The board can be changed since the preview, so the change is planned again
+	selected, unselected, unchanged := []string{}, []string{}, []string{}
+	toggled := []*slack.UserProfile{}
+	for _, profile := range profiles {
+		chosen := planOrderChoice(menuBoard, menuName, mode, profile)
+		switch {
+		case chosen == menuBoard.HasChosen(menuName, profile.RealName):
+			unchanged = append(unchanged, profile.RealName)
+			continue
+		case chosen:
+			selected = append(selected, profile.RealName)
+		default:
+			unselected = append(unselected, profile.RealName)
+		}
 		menuBoard.ToggleMenuByUser(profile, menuName)
+		toggled = append(toggled, profile)
 	}
 
-	handler.Client.UpdateMessage(channel, originalPostTimeStamp, slack.MsgOptionBlocks(menuBoard.ToBlocks()...))
+	if len(toggled) > 0 {
+		if err := SaveOpenBoard(handler, menuBoard); err != nil {
+			return err
+		}
+		if err := RecordToggle(handler, menuBoard, payload.User.ID, menuName, toggled); err != nil {
+			return err
+		}
+		if err := AuditToggles(handler, menuBoard, payload.User.ID, menuName, profileNames(toggled), "order for other"); err != nil {
+			return err
+		}
+	}
+	// Profiles of the users come before the guests, who have no diet
+	for i, userID := range selectedUsers {
+		if menuBoard.HasChosen(menuName, profiles[i].RealName) {
+			if err := WarnDietConflict(handler, menuBoard, userID, menuName); err != nil {
+				return err
+			}
+		}
+	}
+
+	text := fmt.Sprintf("*%s* %s 결과다옹", menuName, orderModeNames[mode])
+	if len(selected) > 0 {
+		text += fmt.Sprintf("\n선택: %s", strings.Join(selected, ", "))
+	}
+	if len(unselected) > 0 {
+		text += fmt.Sprintf("\n취소: %s", strings.Join(unselected, ", "))
+	}
+	if len(unchanged) > 0 {
+		text += fmt.Sprintf("\n그대로: %s", strings.Join(unchanged, ", "))
+	}
+	return ReplyEphemeral(handler, menuBoard, payload.User.ID, text)
 }
 
-// SubmitMenuDelete handles when user submit menu delete view
-func SubmitMenuDelete(handler *Handler, payload *slack.InteractionCallback) {
+// ConfirmMenuDelete updates menu delete view into the confirm step listing the choosers who lose their selection
+func ConfirmMenuDelete(handler *Handler, payload *slack.InteractionCallback) *slack.ViewSubmissionResponse {
 	channel, originalPostTimeStamp := ParseCallbackMetadata(payload.View.PrivateMetadata)
+	menuName := payload.View.State.Values[ids.SubmitMenuDeleteBlock][ids.SubmitMenuInput].SelectedOption.Value
+
+	menuBoard, err := LoadMenuBoard(handler.Client, channel, originalPostTimeStamp)
+	if err != nil {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{ids.SubmitMenuDeleteBlock: "메뉴판을 찾을 수 없다옹"})
+	}
+	menuIndex, ok := menuBoard.MenuNameIndexMap[menuName]
+	if !ok {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{ids.SubmitMenuDeleteBlock: "이미 지워진 메뉴다옹"})
+	}
+	refusal, err := CanDeleteMenu(handler, menuBoard, menuName, payload.User.ID)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to check delete permission: %v\n", err)
+		refusal = "권한을 확인하지 못했다옹. 잠시 후 다시 해달라옹"
+	}
+	if refusal != "" {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{ids.SubmitMenuDeleteBlock: refusal})
+	}
+
+	text := fmt.Sprintf("*%s*를 지울거냐옹?\n", menuName)
+	if choosers := menuBoard.Menus[menuIndex].GetChoosers(); len(choosers) > 0 {
+		text += fmt.Sprintf("고른 사람 %d명의 선택이 사라진다옹\n>`%s`\n", len(choosers), strings.Join(choosers, "` `"))
+	}
+	text += fmt.Sprintf("지운 메뉴는 %d분 동안 메뉴판을 연 사람이 되살릴 수 있다옹", int(trashTTL.Minutes()))
+
+	var modalRequest slack.ModalViewRequest
+	modalRequest.Type = slack.ViewType("modal")
+	modalRequest.Title = slack.NewTextBlockObject("plain_text", "메뉴 삭제", false, false)
+	modalRequest.Close = slack.NewTextBlockObject("plain_text", "Cancel", false, false)
+	modalRequest.Submit = slack.NewTextBlockObject("plain_text", "Delete", false, false)
+	modalRequest.CallbackID = ids.SubmitDeleteMenuConfirmCallback
+	modalRequest.PrivateMetadata = WriteMenuMetadata(menuBoard.ChannelID, menuBoard.Timestamp, menuName)
+	modalRequest.Blocks = slack.Blocks{
+		BlockSet: []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil),
+		},
+	}
+	return slack.NewUpdateViewSubmissionResponse(&modalRequest)
+}
+
+// SubmitMenuDelete handles when user confirms menu delete view, keeping the menu in trash
+func SubmitMenuDelete(handler *Handler, payload *slack.InteractionCallback) error {
+	channel, originalPostTimeStamp, menuName := ParseMenuMetadata(payload.View.PrivateMetadata)
 
 	messageUpdateMutex.Lock()
 	defer messageUpdateMutex.Unlock()
 
-	message := GetMessageFromTimeStamp(handler.Client, channel, originalPostTimeStamp)
-	menuName := payload.View.State.Values[ids.SubmitMenuDeleteBlock][ids.SubmitMenuInput].SelectedOption.Value
-	menuBoard := ParseMenuBlocks(message.Blocks.BlockSet)
+	menuBoard, err := LoadMenuBoard(handler.Client, channel, originalPostTimeStamp)
+	if err != nil {
+		return err
+	}
+	if _, ok := menuBoard.MenuNameIndexMap[menuName]; !ok {
+		return nil
+	}
+	refusal, err := CanDeleteMenu(handler, menuBoard, menuName, payload.User.ID)
+	if err != nil {
+		return err
+	}
+	if refusal != "" {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, refusal)
+	}
+
+	trashed := NewTrashedMenu(handler.TeamID, menuBoard, menuName, payload.User.ID)
+	if err := handler.Store.TrashMenu(trashed); err != nil {
+		return err
+	}
 	menuBoard.DeleteMenu(menuName)
+	if err := SaveOpenBoard(handler, menuBoard); err != nil {
+		return err
+	}
+	if err := RecordDelete(handler, menuBoard, payload.User.ID, trashed); err != nil {
+		return err
+	}
+	if err := AuditBoard(handler, menuBoard, payload.User.ID, AuditDelete, menuName, ""); err != nil {
+		return err
+	}
+
+	canManage, err := CanManageBoard(handler, menuBoard, payload.User.ID)
+	if err != nil {
+		return err
+	}
+	if !canManage {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, fmt.Sprintf("*%s*를 지웠다옹. 메뉴판을 연 사람이 %d분 동안 되살릴 수 있다옹", menuName, int(trashTTL.Minutes())))
+	}
+	_, err = handler.Client.PostEphemeral(menuBoard.ChannelID, payload.User.ID, slack.MsgOptionText(fmt.Sprintf("%s를 지웠다옹", menuName), false), slack.MsgOptionBlocks(NewTrashedMenuBlock(handler, trashed)), slack.MsgOptionTS(menuBoard.ThreadTimestamp))
+	return err
+}
+
+// NewTrashedMenuBlock returns the block showing the deleted menu with its restore button
+func NewTrashedMenuBlock(handler *Handler, trashed TrashedMenu) slack.Block {
+	text := fmt.Sprintf("🗑 *%s* · %d명 선택 · <@%s>님이 %s에 지웠다옹", trashed.MenuName, len(trashed.Choosers), trashed.DeletedBy, handler.InLocation(trashed.DeletedAt).Format("15:04"))
+	restoreBtnTxt := slack.NewTextBlockObject("plain_text", "♻️ 되살리기", true, false)
+	restoreBtn := slack.NewButtonBlockElement(ids.RestoreMenu, WriteMenuMetadata(trashed.ChannelID, trashed.Timestamp, trashed.MenuName), restoreBtnTxt)
+	return slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, slack.NewAccessory(restoreBtn))
+}
+
+// RestoreMenu handles when user clicks restore button of a deleted menu
+func RestoreMenu(handler *Handler, payload *slack.InteractionCallback, value string) error {
+	channel, originalPostTimeStamp, menuName := ParseMenuMetadata(value)
+
+	messageUpdateMutex.Lock()
+	defer messageUpdateMutex.Unlock()
+
+	menuBoard, err := LoadMenuBoard(handler.Client, channel, originalPostTimeStamp)
+	if err != nil {
+		return err
+	}
+	if menuBoard.IsTerminated() {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, "이미 마감된 메뉴판이다옹")
+	}
+	canManage, err := CanManageBoard(handler, menuBoard, payload.User.ID)
+	if err != nil {
+		return err
+	}
+	if !canManage {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, "지운 메뉴는 메뉴판을 연 사람이나 같이 여는 사람만 되살릴 수 있다옹")
+	}
+	if _, ok := menuBoard.MenuNameIndexMap[menuName]; ok {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, fmt.Sprintf("*%s*는 이미 메뉴판에 있다옹", menuName))
+	}
+
+	trashed, ok, err := handler.Store.TakeTrashedMenu(handler.TeamID, channel, menuBoard.Timestamp, menuName)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, fmt.Sprintf("*%s*는 이미 되살렸거나 휴지통에서 비워졌다옹", menuName))
+	}
+	// Reactions of the deleted menu are left on the message, so the restored menu keeps them in reaction mode
+	menuBoard.RestoreMenu(trashed)
+	if err := SaveOpenBoard(handler, menuBoard); err != nil {
+		return err
+	}
+	if err := RecordAdd(handler, menuBoard, payload.User.ID, []string{menuName}); err != nil {
+		return err
+	}
+	if err := AuditBoard(handler, menuBoard, payload.User.ID, AuditRestore, menuName, "trash"); err != nil {
+		return err
+	}
+	return ReplyEphemeral(handler, menuBoard, payload.User.ID, fmt.Sprintf("*%s*를 되살렸다옹", menuName))
+}
+
+// SuggestMenuOptions returns menu options matching the query typed in menu select of view
+func SuggestMenuOptions(handler *Handler, payload *slack.InteractionCallback) []*slack.OptionBlockObject {
+	channel, originalPostTimeStamp := ParseCallbackMetadata(payload.View.PrivateMetadata)
+	menuBoard, err := LoadMenuBoard(handler.Client, channel, originalPostTimeStamp)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to load menu board: %v\n", err)
+		return []*slack.OptionBlockObject{}
+	}
+	return menuBoard.SearchOptionBlockObjects(payload.Value)
+}
+
+// ReplyEphemeral lets only the user know the text in the thread of the board
+func ReplyEphemeral(handler *Handler, menuBoard *MenuBoard, userID string, text string) error {
+	_, err := handler.Client.PostEphemeral(menuBoard.ChannelID, userID, slack.MsgOptionText(text, false), slack.MsgOptionTS(menuBoard.ThreadTimestamp))
+	return err
+}
+
+// failureText is shown to the user when the request could not be handled
+const failureText = "요청을 처리하지 못했다옹 😿 잠시 후 다시 해달라옹"
+
+// busyText is shown to the user when the interaction is dropped since the job queue is full
+const busyText = "지금 너무 바쁘다옹 😿 잠시 후 다시 해달라옹"
 
-	handler.Client.UpdateMessage(channel, originalPostTimeStamp, slack.MsgOptionBlocks(menuBoard.ToBlocks()...))
+// ReportFailure lets the user know ephemerally that the interaction could not be applied
+func ReportFailure(handler *Handler, payload *slack.InteractionCallback, err error) error {
+	// The error may carry internals of slack api, so the user only gets a fixed message
+	handler.Logger.Printf("[ERROR] Interaction of %s failed: %v\n", payload.User.ID, err)
+	return replyToInteraction(handler, payload, failureText)
+}
+
+// ReportBusy lets the user know ephemerally that the interaction is dropped
+func ReportBusy(handler *Handler, payload *slack.InteractionCallback) error {
+	return replyToInteraction(handler, payload, busyText)
+}
+
+// replyToInteraction posts the text only to the user in the channel of the interaction, if it has one
+func replyToInteraction(handler *Handler, payload *slack.InteractionCallback, text string) error {
+	channelID := payload.Channel.ID
+	if channelID == "" && payload.View.PrivateMetadata != "" {
+		channelID, _ = ParseCallbackMetadata(payload.View.PrivateMetadata)
+	}
+	if channelID == "" {
+		return nil
+	}
+	_, err := handler.Client.PostEphemeral(channelID, payload.User.ID, slack.MsgOptionText(text, false))
+	return err
+}
+
+// NewBusyView returns the submitted view with the busy text under it, to be submitted again
+func NewBusyView(view slack.View) *slack.ModalViewRequest {
+	blocks := []slack.Block{}
+	for _, block := range view.Blocks.BlockSet {
+		if contextBlock, ok := block.(*slack.ContextBlock); !ok || contextBlock.BlockID != ids.BusyBlock {
+			blocks = append(blocks, block)
+		}
+	}
+	blocks = append(blocks, slack.NewContextBlock(ids.BusyBlock, slack.NewTextBlockObject("plain_text", busyText, false, false)))
+
+	var modalRequest slack.ModalViewRequest
+	modalRequest.Type = slack.ViewType("modal")
+	modalRequest.Title = view.Title
+	modalRequest.Close = view.Close
+	modalRequest.Submit = view.Submit
+	modalRequest.CallbackID = view.CallbackID
+	modalRequest.PrivateMetadata = view.PrivateMetadata
+	modalRequest.Blocks = slack.Blocks{BlockSet: blocks}
+	return &modalRequest
 }
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slack-waiter-bot/ids"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// menuDeleteSubmission returns the payload of the user picking the menu in the delete view
+func menuDeleteSubmission(userID string, view slack.ModalViewRequest, menuName string) string {
+	return fmt.Sprintf(`{"type":"view_submission","trigger_id":"%s/delete/%d","team":{"id":"T1"},"user":{"id":"%s"},"view":{"callback_id":"%s","private_metadata":%q,"state":{"values":{
+		"%s":{"%s":{"type":"external_select","selected_option":{"value":%q}}}}}}}`,
+		userID, time.Now().UnixNano(), userID, view.CallbackID, view.PrivateMetadata,
+		ids.SubmitMenuDeleteBlock, ids.SubmitMenuInput, menuName)
+}
+
+// confirmSubmission returns the payload of the user submitting the confirm view
+func confirmSubmission(userID string, view *slack.ModalViewRequest) string {
+	return fmt.Sprintf(`{"type":"view_submission","trigger_id":"%s/confirm/%d","team":{"id":"T1"},"user":{"id":"%s"},"view":{"callback_id":"%s","private_metadata":%q}}`,
+		userID, time.Now().UnixNano(), userID, view.CallbackID, view.PrivateMetadata)
+}
+
+func TestTerminateConfirm(t *testing.T) {
+	menuBoard := NewMenuBoard("점심", "U1")
+	for _, tailBlock := range menuBoard.TailBlocks {
+		actionBlock, ok := tailBlock.(*slack.ActionBlock)
+		if !ok {
+			continue
+		}
+		for _, element := range actionBlock.Elements.ElementSet {
+			if button, ok := element.(*slack.ButtonBlockElement); ok && button.ActionID == ids.TerminateMenu {
+				if button.Confirm == nil || button.Confirm.Confirm == nil {
+					t.Fatal("terminate button has no confirm")
+				}
+				return
+			}
+		}
+	}
+	t.Fatal("board has no terminate button")
+}
+
+func TestDeleteAndRestoreMenu(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+	fake.AddUser("U2", "김철수")
+
+	menuBoard := NewMenuBoard("점심", "U1")
+	menuBoard.AddMenu("짜장면", "", "U1")
+	menuBoard.AddMenu("짬뽕", "", "U1")
+	if err := PostNewBoard(handler, "C1", "", menuBoard); err != nil {
+		t.Fatal(err)
+	}
+	payload := &slack.InteractionCallback{User: slack.User{ID: "U2"}}
+	payload.Channel.ID = "C1"
+	payload.Message.Timestamp = menuBoard.Timestamp
+	if err := SelectMenuByUser(handler, payload, "짜장면"); err != nil {
+		t.Fatal(err)
+	}
+	menus := func() map[string][]string {
+		loaded, err := LoadMenuBoard(fake, "C1", menuBoard.Timestamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		menus := map[string][]string{}
+		for _, menu := range loaded.Menus {
+			menus[menu.MenuName] = menu.GetChoosers()
+		}
+		return menus
+	}
+	ephemeral := func(userID string, text string) bool {
+		for _, ephemeral := range fake.Ephemerals() {
+			if ephemeral.UserID == userID && strings.Contains(ephemeral.Text, text) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// The delete view is not applied at once, but updated into the confirm step listing the choosers
+	sendAction(t, handler, blockAction("U1", menuBoard.Timestamp, ids.DeleteMenu, ids.DeleteMenu))
+	waitFor(t, "the delete view opened", func() bool { return len(fake.Views()) == 1 })
+	view := fake.Views()[0]
+	if response := sendAction(t, handler, menuDeleteSubmission("U2", view, "짬뽕")); !strings.Contains(response, "넣은 사람이나 메뉴판을 연 사람만 지울 수 있다옹") {
+		t.Errorf("response of other's menu = %q, want refusal on the menu select", response)
+	}
+	var response slack.ViewSubmissionResponse
+	if err := json.Unmarshal([]byte(sendAction(t, handler, menuDeleteSubmission("U1", view, "짜장면"))), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.ResponseAction != slack.RAUpdate || response.View == nil || response.View.CallbackID != ids.SubmitDeleteMenuConfirmCallback {
+		t.Fatalf("response = %+v, want the confirm view", response)
+	}
+	confirmText, _ := json.Marshal(response.View.Blocks)
+	if !strings.Contains(string(confirmText), "김철수") {
+		t.Errorf("confirm view = %s, want the choosers who lose their selection", confirmText)
+	}
+	if _, ok := menus()["짜장면"]; !ok {
+		t.Fatal("menu is deleted before the confirm")
+	}
+
+	// Confirming deletes the menu into the trash, and shows the host the restore button
+	if body := sendAction(t, handler, confirmSubmission("U1", response.View)); body != "" {
+		t.Errorf("response of confirm = %q, want empty to close the view", body)
+	}
+	waitFor(t, "the menu deleted", func() bool {
+		_, ok := menus()["짜장면"]
+		return !ok
+	})
+	trash := handler.Store.TrashedMenus("T1", "C1", menuBoard.Timestamp)
+	if len(trash) != 1 || trash[0].MenuName != "짜장면" || trash[0].DeletedBy != "U1" {
+		t.Fatalf("trash = %+v, want the deleted menu", trash)
+	}
+	waitFor(t, "the restore button", func() bool { return ephemeral("U1", "짜장면를 지웠다옹") })
+	restoreValue := WriteMenuMetadata("C1", menuBoard.Timestamp, "짜장면")
+
+	// Only the hosts restore the menu, back at its position with its choosers
+	sendAction(t, handler, blockAction("U2", menuBoard.Timestamp, ids.RestoreMenu, restoreValue))
+	waitFor(t, "the refusal", func() bool {
+		return ephemeral("U2", "메뉴판을 연 사람이나 같이 여는 사람만 되살릴 수 있다옹")
+	})
+	sendAction(t, handler, blockAction("U1", menuBoard.Timestamp, ids.RestoreMenu, restoreValue))
+	waitFor(t, "the menu restored", func() bool { return ephemeral("U1", "*짜장면*를 되살렸다옹") })
+	if got := menus(); !reflect.DeepEqual(got, map[string][]string{"짜장면": {"김철수"}, "짬뽕": {}}) {
+		t.Errorf("menus = %v, want the menu restored with its choosers", got)
+	}
+	loaded, err := LoadMenuBoard(fake, "C1", menuBoard.Timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Menus[0].MenuName != "짜장면" {
+		t.Errorf("first menu = %q, want the menu restored at its position", loaded.Menus[0].MenuName)
+	}
+	if trash := handler.Store.TrashedMenus("T1", "C1", menuBoard.Timestamp); len(trash) != 0 {
+		t.Errorf("trash = %+v, want the restored menu taken out", trash)
+	}
+
+	// Restoring twice is told the menu is already there
+	sendAction(t, handler, blockAction("U1", menuBoard.Timestamp, ids.RestoreMenu, restoreValue))
+	waitFor(t, "the second restore refused", func() bool { return ephemeral("U1", "*짜장면*는 이미 메뉴판에 있다옹") })
+}
+
+// orderForOtherSubmission returns the payload of the user picking the menu, people and mode in the order for others view
+func orderForOtherSubmission(userID string, view slack.ModalViewRequest, menuName string, people []string, mode string) string {
+	selectedUsers, _ := json.Marshal(people)
+	return fmt.Sprintf(`{"type":"view_submission","trigger_id":"%s/order/%d","team":{"id":"T1"},"user":{"id":"%s"},"view":{"callback_id":"%s","private_metadata":%q,"state":{"values":{
+		"%s":{"%s":{"type":"external_select","selected_option":{"value":%q}}},
+		"%s":{"%s":{"type":"multi_users_select","selected_users":%s}},
+		"%s":{"%s":{"type":"radio_buttons","selected_option":{"value":%q}}}}}}}`,
+		userID, time.Now().UnixNano(), userID, view.CallbackID, view.PrivateMetadata,
+		ids.SubmitMenuInputBlock, ids.SubmitMenuInput, menuName,
+		ids.SubmitMenuSelectPeopleBlock, ids.SubmitMenuPeople, selectedUsers,
+		ids.OrderModeBlock, ids.OrderMode, mode)
+}
+
+func TestOrderForOther(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+	fake.AddUser("U2", "김철수")
+	fake.AddUser("U3", "박영희")
+
+	menuBoard := NewMenuBoard("점심", "U1")
+	menuBoard.AddMenu("짜장면", "", "U1")
+	if err := PostNewBoard(handler, "C1", "", menuBoard); err != nil {
+		t.Fatal(err)
+	}
+	payload := &slack.InteractionCallback{User: slack.User{ID: "U2"}}
+	payload.Channel.ID = "C1"
+	payload.Message.Timestamp = menuBoard.Timestamp
+	if err := SelectMenuByUser(handler, payload, "짜장면"); err != nil {
+		t.Fatal(err)
+	}
+	choosers := func() []string {
+		loaded, err := LoadMenuBoard(fake, "C1", menuBoard.Timestamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return loaded.Menus[loaded.MenuNameIndexMap["짜장면"]].GetChoosers()
+	}
+
+	sendAction(t, handler, blockAction("U1", menuBoard.Timestamp, ids.OrderForOther, ids.OrderForOther))
+	waitFor(t, "the order for others view opened", func() bool { return len(fake.Views()) == 1 })
+	view := fake.Views()[0]
+
+	tests := []struct {
+		mode         string
+		people       []string
+		wantPreview  []string
+		wantResult   []string
+		wantChoosers []string
+	}{
+		// Adding keeps who already chose the menu, instead of toggling them off
+		{mode: OrderModeAdd, people: []string{"U2", "U3"}, wantPreview: []string{"김철수: 고름 (그대로)", "박영희: 안 고름 → *고름*"}, wantResult: []string{"선택: 박영희", "그대로: 김철수"}, wantChoosers: []string{"김철수", "박영희"}},
+		{mode: OrderModeRemove, people: []string{"U2"}, wantPreview: []string{"김철수: 고름 → *안 고름*"}, wantResult: []string{"취소: 김철수"}, wantChoosers: []string{"박영희"}},
+		{mode: OrderModeToggle, people: []string{"U2", "U3"}, wantPreview: []string{"김철수: 안 고름 → *고름*", "박영희: 고름 → *안 고름*"}, wantResult: []string{"선택: 김철수", "취소: 박영희"}, wantChoosers: []string{"김철수"}},
+	}
+	for _, test := range tests {
+		var response slack.ViewSubmissionResponse
+		if err := json.Unmarshal([]byte(sendAction(t, handler, orderForOtherSubmission("U1", view, "짜장면", test.people, test.mode))), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.ResponseAction != slack.RAUpdate || response.View == nil || response.View.CallbackID != ids.SubmitOrderForOtherConfirmCallback {
+			t.Fatalf("response of %s = %+v, want the preview", test.mode, response)
+		}
+		preview, _ := json.Marshal(response.View.Blocks)
+		for _, line := range test.wantPreview {
+			if !strings.Contains(string(preview), line) {
+				t.Errorf("preview of %s = %s, want %q", test.mode, preview, line)
+			}
+		}
+
+		numEphemerals := len(fake.Ephemerals())
+		if body := sendAction(t, handler, confirmSubmission("U1", response.View)); body != "" {
+			t.Errorf("response of confirm = %q, want empty to close the view", body)
+		}
+		waitFor(t, "the result reported", func() bool { return len(fake.Ephemerals()) > numEphemerals })
+		result := fake.Ephemerals()[numEphemerals]
+		for _, line := range test.wantResult {
+			if result.UserID != "U1" || !strings.Contains(result.Text, line) {
+				t.Errorf("result of %s = %+v, want %q to the submitter", test.mode, result, line)
+			}
+		}
+		if got := choosers(); !reflect.DeepEqual(got, test.wantChoosers) {
+			t.Errorf("choosers after %s = %v, want %v", test.mode, got, test.wantChoosers)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"fmt"
+	"slack-waiter-bot/ids"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// maxHomeBoards bounds the number of open boards of the user listed in App Home
+const maxHomeBoards = 20
+const numHomeHistory = 5
+
+// PublishAppHome publishes App Home of the user with open boards, picks and order history
+func PublishAppHome(handler *Handler, userID string) error {
+	profile, err := handler.Profiles.GetProfile(userID)
+	if err != nil {
+		return err
+	}
+
+	headerText := slack.NewTextBlockObject("plain_text", "🍽 Waiter Bot", true, false)
+	startBoardBtnTxt := slack.NewTextBlockObject("plain_text", "➕ 메뉴판 열기", true, false)
+	startBoardBtn := slack.NewButtonBlockElement(ids.OpenStartBoard, ids.OpenStartBoard, startBoardBtnTxt).WithStyle(slack.StylePrimary)
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(headerText),
+		slack.NewActionBlock("", startBoardBtn),
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", "*열려 있는 메뉴판*", false, false), nil, nil),
+	}
+
+	boardBlocks := appHomeBoardBlocks(handler, userID, profile.RealName)
+	if len(boardBlocks) == 0 {
+		boardBlocks = append(boardBlocks, slack.NewContextBlock("", slack.NewTextBlockObject("plain_text", "지금 참여 중인 메뉴판이 없다옹", false, false)))
+	}
+	blocks = append(blocks, boardBlocks...)
+	blocks = append(blocks, slack.NewDividerBlock())
+	blocks = append(blocks, appHomeHistoryBlocks(handler, profile.RealName)...)
+	blocks = append(blocks, slack.NewDividerBlock())
+	blocks = append(blocks, appHomeDietBlocks(handler, userID)...)
+
+	homeView := slack.HomeTabViewRequest{
+		Type:   slack.VTHomeTab,
+		Blocks: slack.Blocks{BlockSet: blocks},
+	}
+	_, err = handler.Client.PublishView(userID, homeView, "")
+	return err
+}
+
+// appHomeBoardBlocks lists open boards the user hosts or picked menus on from the store, without loading each board
+func appHomeBoardBlocks(handler *Handler, userID string, realName string) []slack.Block {
+	blocks := []slack.Block{}
+	for _, activeBoard := range handler.Store.ActiveBoards(handler.TeamID) {
+		picks := activeBoard.Picks(realName)
+		if !activeBoard.IsHost(userID) && len(picks) == 0 {
+			continue
+		}
+
+		title := activeBoard.Title
+		if activeBoard.Permalink != "" {
+			title = fmt.Sprintf("<%s|%s>", activeBoard.Permalink, activeBoard.Title)
+		}
+		pickText := "아직 고르지 않았다옹"
+		if len(picks) > 0 {
+			pickText = "내 메뉴: " + strings.Join(picks, ", ")
+		}
+		text := fmt.Sprintf("*%s* <#%s> · <@%s>\n%s", title, activeBoard.ChannelID, activeBoard.HostUserID, pickText)
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil))
+		if len(blocks) == maxHomeBoards {
+			break
+		}
+	}
+	return blocks
+}
+
+// appHomeHistoryBlocks shows totals of this month and the latest orders of the user
+func appHomeHistoryBlocks(handler *Handler, realName string) []slack.Block {
+	now := handler.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	monthCounts := map[string]int{}
+	numMonthOrders := 0
+	history := ""
+	numHistory := 0
+	for _, record := range handler.Store.TeamBoards(handler.TeamID) {
+		for _, menu := range record.Menus {
+			chosen := false
+			for _, chooser := range menu.Choosers {
+				chosen = chosen || chooser == realName
+			}
+			if !chosen {
+				continue
+			}
+			if !record.TerminatedAt.Before(monthStart) {
+				monthCounts[menu.MenuName]++
+				numMonthOrders++
+			}
+			if numHistory < numHomeHistory {
+				history += fmt.Sprintf("• %s *%s* %s <#%s>\n", handler.InLocation(record.TerminatedAt).Format("01/02"), menu.MenuName, record.Title, record.ChannelID)
+				numHistory++
+			}
+		}
+	}
+
+	monthText := fmt.Sprintf("*이번 달 주문* %d그릇\n", numMonthOrders)
+	for _, menuName := range rankKeys(monthCounts, numStatsRanks) {
+		monthText += fmt.Sprintf("• %s ×%d\n", menuName, monthCounts[menuName])
+	}
+	if history == "" {
+		history = "아직 주문한 적이 없다옹"
+	}
+	return []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", monthText, false, false), nil, nil),
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", "*최근 주문*\n"+history, false, false), nil, nil),
+	}
+}
+
+// appHomeDietBlocks shows the dietary restrictions of the user as checkboxes saved on change
+func appHomeDietBlocks(handler *Handler, userID string) []slack.Block {
+	dietOptions := NewDietOptions()
+	dietElement := slack.NewCheckboxGroupsBlockElement(ids.DietRestrictions, dietOptions...)
+	for _, restriction := range handler.Store.DietProfile(handler.TeamID, userID).Restrictions {
+		for _, option := range dietOptions {
+			if option.Value == restriction {
+				dietElement.InitialOptions = append(dietElement.InitialOptions, option)
+			}
+		}
+	}
+	dietText := "*내 식단*\n고른 메뉴가 식단과 맞지 않으면 알려주고, 마감할 때 메뉴판을 연 사람에게도 보여준다옹"
+	return []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", dietText, false, false), nil, nil),
+		slack.NewActionBlock("", dietElement),
+	}
+}
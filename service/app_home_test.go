@@ -0,0 +1,68 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestPublishAppHome(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+	fake.AddUser("U2", "손님")
+
+	// The oldest board of the team is the one the user picked a menu on
+	menuBoard := NewMenuBoard("점심", "U1")
+	menuBoard.AddMenu("짜장면", "", "U1")
+	menuBoard.AddMenu("짬뽕", "", "U1")
+	if err := PostNewBoard(handler, "C1", "", menuBoard); err != nil {
+		t.Fatal(err)
+	}
+	payload := &slack.InteractionCallback{User: slack.User{ID: "U2"}}
+	payload.Channel.ID = "C1"
+	payload.Message.Timestamp = menuBoard.Timestamp
+	if err := SelectMenuByUser(handler, payload, "짜장면"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Boards of others fill more than the listed boards, and are listed from the store without messages
+	if err := handler.Store.AddActiveBoard(ActiveBoard{TeamID: "T1", ChannelID: "C2", Timestamp: "1.0", Title: "같이 여는 메뉴판", HostUserID: "U1", CohostUserIDs: []string{"U2"}}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxHomeBoards+5; i++ {
+		activeBoard := ActiveBoard{TeamID: "T1", ChannelID: "C3", Timestamp: fmt.Sprintf("%d.0", i+2), Title: "남의 메뉴판", HostUserID: "U1"}
+		if err := handler.Store.AddActiveBoard(activeBoard); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := PublishAppHome(handler, "U2"); err != nil {
+		t.Fatal(err)
+	}
+	homeView, ok := fake.HomeView("U2")
+	if !ok {
+		t.Fatal("home view is not published")
+	}
+	texts := ""
+	for _, block := range homeView.Blocks.BlockSet {
+		if section, ok := block.(*slack.SectionBlock); ok {
+			texts += section.Text.Text + "\n"
+		}
+	}
+
+	permalink, err := fake.GetPermalink(&slack.PermalinkParameters{Channel: "C1", Ts: menuBoard.Timestamp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(texts, "<"+permalink+"|점심>") || !strings.Contains(texts, "내 메뉴: 짜장면") {
+		t.Errorf("home = %q, want the board picked on with its permalink and picks", texts)
+	}
+	if !strings.Contains(texts, "같이 여는 메뉴판") {
+		t.Errorf("home = %q, want the board hosted together", texts)
+	}
+	if strings.Contains(texts, "남의 메뉴판") {
+		t.Errorf("home = %q, want no boards of others", texts)
+	}
+}
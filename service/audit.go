@@ -0,0 +1,185 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// maxAuditLineSize bounds the size of a line read from the audit log file
+const maxAuditLineSize = 1024 * 1024
+
+// Actions of audit entries
+const (
+	AuditOpen      = "open"
+	AuditSelect    = "select"
+	AuditUnselect  = "unselect"
+	AuditAdd       = "add"
+	AuditDelete    = "delete"
+	AuditRestore   = "restore"
+	AuditDeadline  = "deadline"
+	AuditCohost    = "cohost"
+	AuditGuest     = "guest"
+	AuditTerminate = "terminate"
+	AuditReopen    = "reopen"
+)
+
+// AuditEntry is a change of a board kept in the audit log
+type AuditEntry struct {
+	TeamID    string    `json:"team_id"`
+	ChannelID string    `json:"channel_id"`
+	Timestamp string    `json:"timestamp"`
+	At        time.Time `json:"at"`
+	// UserID is who made the change, empty for changes made by the bot like closing at the deadline
+	UserID   string `json:"user_id"`
+	Action   string `json:"action"`
+	MenuName string `json:"menu_name,omitempty"`
+	// Chooser is whose choice is changed, who can be other than the user when ordered on behalf
+	Chooser string `json:"chooser,omitempty"`
+	// Detail is how the change is made, like "reaction" or "undo", or the value set
+	Detail string `json:"detail,omitempty"`
+}
+
+// AuditLog is the append-only log of board changes, written as JSON Lines to the file when path is given
+type AuditLog struct {
+	Path    string
+	mutex   sync.Mutex
+	entries []AuditEntry
+}
+
+// NewAuditLog creates audit log appending to the file, or kept in memory when path is empty
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{Path: path}
+}
+
+// Append writes the entry at the end of the log
+func (a *AuditLog) Append(entry AuditEntry) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.Path == "" {
+		a.entries = append(a.entries, entry)
+		return nil
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(a.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// BoardEntries returns entries of the board, oldest first
+func (a *AuditLog) BoardEntries(teamID string, channelID string, timestamp string) ([]AuditEntry, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	entries := []AuditEntry{}
+	isBoardEntry := func(entry AuditEntry) bool {
+		return entry.TeamID == teamID && entry.ChannelID == channelID && entry.Timestamp == timestamp
+	}
+	if a.Path == "" {
+		for _, entry := range a.entries {
+			if isBoardEntry(entry) {
+				entries = append(entries, entry)
+			}
+		}
+		return entries, nil
+	}
+
+	file, err := os.Open(a.Path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxAuditLineSize)
+	for scanner.Scan() {
+		var entry AuditEntry
+		// A line cut by a crash while writing is skipped rather than failing the whole log
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if isBoardEntry(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// AuditBoard appends the change of the board made by the user to the audit log
+func AuditBoard(handler *Handler, menuBoard *MenuBoard, userID string, action string, menuName string, detail string) error {
+	return handler.Audit.Append(AuditEntry{
+		TeamID:    handler.TeamID,
+		ChannelID: menuBoard.ChannelID,
+		Timestamp: menuBoard.Timestamp,
+		At:        time.Now(),
+		UserID:    userID,
+		Action:    action,
+		MenuName:  menuName,
+		Detail:    detail,
+	})
+}
+
+// AuditGuestAdd appends the guest added by the user to the audit log, with the sponsor as the detail
+func AuditGuestAdd(handler *Handler, menuBoard *MenuBoard, userID string, guest Guest) error {
+	return handler.Audit.Append(AuditEntry{
+		TeamID:    handler.TeamID,
+		ChannelID: menuBoard.ChannelID,
+		Timestamp: menuBoard.Timestamp,
+		At:        time.Now(),
+		UserID:    userID,
+		Action:    AuditGuest,
+		Chooser:   guest.ChooserName(),
+		Detail:    guest.SponsorUserID,
+	})
+}
+
+// profileNames returns real names of the profiles, which choosers are kept as
+func profileNames(profiles []*slack.UserProfile) []string {
+	names := []string{}
+	for _, profile := range profiles {
+		names = append(names, profile.RealName)
+	}
+	return names
+}
+
+// AuditToggles appends the choices of the choosers toggled by the user to the audit log, reading the result from the board
+func AuditToggles(handler *Handler, menuBoard *MenuBoard, userID string, menuName string, choosers []string, detail string) error {
+	for _, chooser := range choosers {
+		action := AuditUnselect
+		if menuBoard.HasChosen(menuName, chooser) {
+			action = AuditSelect
+		}
+		entry := AuditEntry{
+			TeamID:    handler.TeamID,
+			ChannelID: menuBoard.ChannelID,
+			Timestamp: menuBoard.Timestamp,
+			At:        time.Now(),
+			UserID:    userID,
+			Action:    action,
+			MenuName:  menuName,
+			Chooser:   chooser,
+			Detail:    detail,
+		}
+		if err := handler.Audit.Append(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"slack-waiter-bot/ids"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// numAuditViewEntries bounds the number of entries shown in audit log view, the whole log is exported
+const numAuditViewEntries = 40
+
+var auditActionNames = map[string]string{
+	AuditOpen:      "📋 열기",
+	AuditSelect:    "👆 선택",
+	AuditUnselect:  "✖️ 선택 취소",
+	AuditAdd:       "➕ 추가",
+	AuditDelete:    "➖ 삭제",
+	AuditRestore:   "♻️ 되살림",
+	AuditDeadline:  "⏰ 마감 시간",
+	AuditCohost:    "👑 같이 여는 사람",
+	AuditGuest:     "👤 손님",
+	AuditTerminate: "🚫 마감",
+	AuditReopen:    "↩️ 다시 열기",
+}
+
+var mrkdwnEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// OpenAuditLog handles when user runs the audit log shortcut on a board message
+func OpenAuditLog(handler *Handler, payload *slack.InteractionCallback) error {
+	blocks := payload.Message.Blocks.BlockSet
+	_, isContinuation := ParseContinuationBlock(blocks)
+	if payload.Message.User != handler.BotUserID || len(blocks) == 0 || (blocks[0].BlockType() != slack.MBTHeader && !isContinuation) {
+		_, err := handler.Client.PostEphemeral(payload.Channel.ID, payload.User.ID, slack.MsgOptionText("메뉴판 메시지에서 불러달라옹", false))
+		return err
+	}
+	menuBoard, err := LoadMenuBoard(handler.Client, payload.Channel.ID, payload.Message.Timestamp)
+	if err != nil {
+		return err
+	}
+	canManage, err := CanManageBoard(handler, menuBoard, payload.User.ID)
+	if err != nil {
+		return err
+	}
+	if !canManage {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, "변경 기록은 메뉴판을 연 사람이나 같이 여는 사람만 볼 수 있다옹")
+	}
+
+	entries, err := handler.Audit.BoardEntries(handler.TeamID, menuBoard.ChannelID, menuBoard.Timestamp)
+	if err != nil {
+		return err
+	}
+	_, err = handler.Client.OpenView(payload.TriggerID, NewAuditLogView(handler, menuBoard.ChannelID, menuBoard.Timestamp, entries, "", ""))
+	return err
+}
+
+// SearchAuditLog updates audit log view with entries of the user and the menu searched
+func SearchAuditLog(handler *Handler, payload *slack.InteractionCallback) *slack.ViewSubmissionResponse {
+	channelID, timestamp := ParseCallbackMetadata(payload.View.PrivateMetadata)
+	userID := payload.View.State.Values[ids.AuditUserBlock][ids.AuditUser].SelectedUser
+	menuQuery := strings.TrimSpace(payload.View.State.Values[ids.AuditMenuBlock][ids.AuditMenu].Value)
+
+	// The view may be kept open after the user stops hosting the board
+	menuBoard, err := LoadMenuBoard(handler.Client, channelID, timestamp)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to load menu board: %v\n", err)
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{ids.AuditMenuBlock: "메뉴판을 찾을 수 없다옹"})
+	}
+	canManage, err := CanManageBoard(handler, menuBoard, payload.User.ID)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to check permission: %v\n", err)
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{ids.AuditMenuBlock: "권한을 확인하지 못했다옹"})
+	}
+	if !canManage {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{ids.AuditMenuBlock: "변경 기록은 메뉴판을 연 사람이나 같이 여는 사람만 볼 수 있다옹"})
+	}
+
+	entries, err := handler.Audit.BoardEntries(handler.TeamID, channelID, timestamp)
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to read audit log: %v\n", err)
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{ids.AuditMenuBlock: "변경 기록을 읽지 못했다옹"})
+	}
+	// Choices are changed on behalf of others too, so the user is matched as the chooser as well
+	chooser := ""
+	if userID != "" {
+		profile, err := handler.Profiles.GetProfile(userID)
+		if err != nil {
+			handler.Logger.Printf("[ERROR] Failed to get profile: %v\n", err)
+			return slack.NewErrorsViewSubmissionResponse(map[string]string{ids.AuditUserBlock: "사람을 찾지 못했다옹"})
+		}
+		chooser = profile.RealName
+	}
+
+	matched := []AuditEntry{}
+	for _, entry := range entries {
+		if userID != "" && entry.UserID != userID && entry.Chooser != chooser {
+			continue
+		}
+		if menuQuery != "" && !strings.Contains(strings.ToLower(entry.MenuName), strings.ToLower(menuQuery)) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	view := NewAuditLogView(handler, channelID, timestamp, matched, userID, menuQuery)
+	return slack.NewUpdateViewSubmissionResponse(&view)
+}
+
+// NewAuditLogView makes the view of audit log entries with the search inputs and the export button
+func NewAuditLogView(handler *Handler, channelID string, timestamp string, entries []AuditEntry, userID string, menuQuery string) slack.ModalViewRequest {
+	// User Select Block
+	userSelectText := slack.NewTextBlockObject("plain_text", "바꾼 사람이나 고른 사람", false, false)
+	userSelectElement := slack.NewOptionsSelectBlockElement(slack.OptTypeUser, nil, ids.AuditUser)
+	userSelectElement.InitialUser = userID
+	userSelect := slack.NewInputBlock(ids.AuditUserBlock, userSelectText, userSelectElement)
+	userSelect.Optional = true
+
+	// Menu Input Block
+	menuInputText := slack.NewTextBlockObject("plain_text", "메뉴 이름", false, false)
+	menuInputElement := slack.NewPlainTextInputBlockElement(nil, ids.AuditMenu)
+	menuInputElement.InitialValue = menuQuery
+	menuInput := slack.NewInputBlock(ids.AuditMenuBlock, menuInputText, menuInputElement)
+	menuInput.Optional = true
+
+	summaryText := fmt.Sprintf("<#%s> 메뉴판의 변경 %d건", channelID, len(entries))
+	if len(entries) > numAuditViewEntries {
+		summaryText += fmt.Sprintf(", 최근 %d건만 보여준다옹", numAuditViewEntries)
+	}
+	blocks := []slack.Block{userSelect, menuInput, slack.NewDividerBlock(), slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", summaryText, false, false))}
+
+	// Section text is limited to 3000 characters, so lines are split into sections
+	text := ""
+	for i := len(entries) - 1; i >= 0 && i >= len(entries)-numAuditViewEntries; i-- {
+		line := FormatAuditEntry(handler, entries[i]) + "\n"
+		if len(text)+len(line) > 2900 {
+			blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil))
+			text = ""
+		}
+		text += line
+	}
+	if text != "" {
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil))
+	}
+
+	exportBtnTxt := slack.NewTextBlockObject("plain_text", "📄 JSON Lines로 내보내기", true, false)
+	exportBtn := slack.NewButtonBlockElement(ids.ExportAuditLog, ids.ExportAuditLog, exportBtnTxt)
+	blocks = append(blocks, slack.NewActionBlock("", exportBtn))
+
+	var modalRequest slack.ModalViewRequest
+	modalRequest.Type = slack.ViewType("modal")
+	modalRequest.Title = slack.NewTextBlockObject("plain_text", "변경 기록", false, false)
+	modalRequest.Close = slack.NewTextBlockObject("plain_text", "Close", false, false)
+	modalRequest.Submit = slack.NewTextBlockObject("plain_text", "Search", false, false)
+	modalRequest.CallbackID = ids.SubmitAuditLogCallback
+	modalRequest.PrivateMetadata = WriteCallbackMetadata(channelID, timestamp)
+	modalRequest.Blocks = slack.Blocks{BlockSet: blocks}
+	return modalRequest
+}
+
+// FormatAuditEntry returns a line of mrkdwn describing the entry, with times in the location of the handler
+func FormatAuditEntry(handler *Handler, entry AuditEntry) string {
+	actor := "🤖"
+	if entry.UserID != "" {
+		actor = "<@" + entry.UserID + ">"
+	}
+	text := fmt.Sprintf("`%s` %s %s", handler.InLocation(entry.At).Format("01/02 15:04:05"), actor, auditActionNames[entry.Action])
+	if entry.MenuName != "" {
+		text += " *" + mrkdwnEscaper.Replace(entry.MenuName) + "*"
+	}
+	if entry.Chooser != "" {
+		text += " → " + mrkdwnEscaper.Replace(entry.Chooser)
+	}
+
+	switch {
+	case entry.Action == AuditCohost:
+		text += " <@" + entry.Detail + ">"
+	case entry.Action == AuditGuest:
+		text += ", <@" + entry.Detail + ">님이 낸다옹"
+	case entry.Action == AuditDeadline:
+		if deadline, err := time.Parse(time.RFC3339, entry.Detail); err == nil {
+			text += " " + handler.InLocation(deadline).Format("15:04")
+		}
+	case entry.Detail != "":
+		text += " _(" + mrkdwnEscaper.Replace(entry.Detail) + ")_"
+	}
+	return text
+}
+
+// ExportAuditLog handles when user clicks export button of audit log view, sending the whole log of the board as JSON Lines file by direct message
+func ExportAuditLog(handler *Handler, payload *slack.InteractionCallback) error {
+	channelID, timestamp := ParseCallbackMetadata(payload.View.PrivateMetadata)
+	menuBoard, err := LoadMenuBoard(handler.Client, channelID, timestamp)
+	if err != nil {
+		return err
+	}
+	canManage, err := CanManageBoard(handler, menuBoard, payload.User.ID)
+	if err != nil {
+		return err
+	}
+	if !canManage {
+		return ReplyEphemeral(handler, menuBoard, payload.User.ID, "변경 기록은 메뉴판을 연 사람이나 같이 여는 사람만 내보낼 수 있다옹")
+	}
+
+	entries, err := handler.Audit.BoardEntries(handler.TeamID, channelID, timestamp)
+	if err != nil {
+		return err
+	}
+	content := ""
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		content += string(line) + "\n"
+	}
+
+	directChannel, _, _, err := handler.Client.OpenConversation(&slack.OpenConversationParameters{Users: []string{payload.User.ID}, ReturnIM: true})
+	if err != nil {
+		return err
+	}
+	_, err = handler.Client.UploadFile(slack.FileUploadParameters{
+		Content:        content,
+		Filetype:       "text",
+		Filename:       fmt.Sprintf("waiter-audit-%s-%s.jsonl", channelID, timestamp),
+		Title:          menuBoard.Title + " 변경 기록",
+		InitialComment: fmt.Sprintf("<#%s> *%s* 메뉴판의 변경 기록 %d건이다옹", channelID, mrkdwnEscaper.Replace(menuBoard.Title), len(entries)),
+		Channels:       []string{directChannel.ID},
+	})
+	return err
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"slack-waiter-bot/ids"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestFormatAuditEntry(t *testing.T) {
+	handler := &Handler{Location: time.FixedZone("KST", 9*60*60)}
+	at := time.Date(2021, 6, 1, 3, 0, 0, 0, time.UTC)
+
+	text := FormatAuditEntry(handler, AuditEntry{UserID: "U1", Action: AuditSelect, MenuName: "짜장면", At: at})
+	if !strings.HasPrefix(text, "`06/01 12:00:00`") {
+		t.Errorf("text = %q, want the time in the location of the handler", text)
+	}
+	text = FormatAuditEntry(handler, AuditEntry{UserID: "U1", Action: AuditDeadline, Detail: at.Add(30 * time.Minute).Format(time.RFC3339), At: at})
+	if !strings.HasSuffix(text, " 12:30") {
+		t.Errorf("text = %q, want the deadline in the location of the handler", text)
+	}
+}
+
+func TestSearchAuditLog(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+	fake.AddUser("U2", "손님")
+
+	menuBoard := NewMenuBoard("점심", "U1")
+	menuBoard.AddMenu("짜장면", "", "U1")
+	if err := PostMenuBoard(handler.Client, menuBoard, "C1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := AuditBoard(handler, menuBoard, "U1", AuditAdd, "짜장면", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(userID string) *slack.ViewSubmissionResponse {
+		return SearchAuditLog(handler, &slack.InteractionCallback{
+			User: slack.User{ID: userID},
+			View: slack.View{
+				PrivateMetadata: WriteCallbackMetadata("C1", menuBoard.Timestamp),
+				State:           &slack.ViewState{Values: map[string]map[string]slack.BlockAction{}},
+			},
+		})
+	}
+	if response := search("U2"); response.ResponseAction != slack.RAErrors || response.Errors[ids.AuditMenuBlock] == "" {
+		t.Errorf("search by other user = %+v, want errors", response)
+	}
+	response := search("U1")
+	if response.ResponseAction != slack.RAUpdate || response.View == nil {
+		t.Fatalf("search by host = %+v, want updated view", response)
+	}
+	found := false
+	for _, block := range response.View.Blocks.BlockSet {
+		if section, ok := block.(*slack.SectionBlock); ok && strings.Contains(section.Text.Text, "짜장면") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("updated view does not show the entry")
+	}
+}
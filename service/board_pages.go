@@ -0,0 +1,181 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"slack-waiter-bot/ids"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// maxBlocksPerMessage is the number of blocks slack allows in a message
+const maxBlocksPerMessage = 50
+
+// ErrMenuBoardNotFound is returned when the menu board message does not exist
+var ErrMenuBoardNotFound = errors.New("menu board not found")
+
+// NewContinuationBlock returns the first block of continuation message which points the board message
+func NewContinuationBlock(boardTimeStamp string) *slack.ContextBlock {
+	text := slack.NewTextBlockObject("plain_text", "⤴️ 위 메뉴판에서 이어진다옹", false, false)
+	return slack.NewContextBlock(ids.BoardContinuationBlock+boardTimeStamp, text)
+}
+
+// ParseContinuationBlock returns board message timestamp if blocks are continuation message of a board
+func ParseContinuationBlock(blocks []slack.Block) (string, bool) {
+	if len(blocks) == 0 {
+		return "", false
+	}
+	contextBlock, ok := blocks[0].(*slack.ContextBlock)
+	if !ok || !strings.HasPrefix(contextBlock.BlockID, ids.BoardContinuationBlock) {
+		return "", false
+	}
+	return strings.TrimPrefix(contextBlock.BlockID, ids.BoardContinuationBlock), true
+}
+
+// LoadMenuBoard loads a whole menu board from the board message or any of its continuation messages
+func LoadMenuBoard(client SlackAPI, channelID string, timestamp string) (*MenuBoard, error) {
+	message, err := GetMessageFromTimeStamp(client, channelID, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if boardTimeStamp, ok := ParseContinuationBlock(message.Blocks.BlockSet); ok {
+		message, err = GetMessageFromTimeStamp(client, channelID, boardTimeStamp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	menuBoard := ParseMenuBlocks(message.Blocks.BlockSet)
+	menuBoard.ChannelID = channelID
+	menuBoard.Timestamp = message.Timestamp
+	menuBoard.ThreadTimestamp = message.ThreadTimestamp
+	menuBoard.ParentUserID = message.ParentUserId
+	if menuBoard.ThreadTimestamp == "" {
+		menuBoard.ThreadTimestamp = message.Timestamp
+	}
+
+	replies, _, _, err := client.GetConversationReplies(&slack.GetConversationRepliesParameters{ChannelID: channelID, Timestamp: menuBoard.ThreadTimestamp})
+	if err != nil {
+		return nil, err
+	}
+	for _, reply := range replies {
+		if boardTimeStamp, ok := ParseContinuationBlock(reply.Blocks.BlockSet); ok && boardTimeStamp == menuBoard.Timestamp {
+			menuBoard.appendMenuBlocks(reply.Blocks.BlockSet[1:])
+			menuBoard.ContinuationTimestamps = append(menuBoard.ContinuationTimestamps, reply.Timestamp)
+		}
+	}
+	return menuBoard, nil
+}
+
+// PostMenuBoard posts new menu board into the thread, or as a new thread when thread timestamp is empty
+func PostMenuBoard(client SlackAPI, mb *MenuBoard, channelID string, threadTimestamp string) error {
+	options := []slack.MsgOption{slack.MsgOptionBlocks(mb.ToBlocks()...), slack.MsgOptionText(mb.Title, false)}
+	if threadTimestamp != "" {
+		options = append(options, slack.MsgOptionTS(threadTimestamp))
+	}
+	_, timestamp, err := client.PostMessage(channelID, options...)
+	if err != nil {
+		return err
+	}
+
+	mb.ChannelID = channelID
+	mb.Timestamp = timestamp
+	mb.ThreadTimestamp = threadTimestamp
+	if mb.ThreadTimestamp == "" {
+		mb.ThreadTimestamp = timestamp
+	}
+	return nil
+}
+
+// SaveMenuBoard updates the board message, posting or deleting continuation messages as the board grows or shrinks
+func SaveMenuBoard(client SlackAPI, mb *MenuBoard) error {
+	pages := mb.ToPages()
+	if _, _, _, err := client.UpdateMessage(mb.ChannelID, mb.Timestamp, slack.MsgOptionBlocks(pages[0]...)); err != nil {
+		return err
+	}
+
+	// Continuation timestamps follow the messages as they are written, so that saving again after a failure cleans them up
+	for i, page := range pages[1:] {
+		if i < len(mb.ContinuationTimestamps) {
+			if _, _, _, err := client.UpdateMessage(mb.ChannelID, mb.ContinuationTimestamps[i], slack.MsgOptionBlocks(page...)); err != nil {
+				return err
+			}
+			continue
+		}
+		_, timestamp, err := client.PostMessage(mb.ChannelID, slack.MsgOptionBlocks(page...), slack.MsgOptionTS(mb.ThreadTimestamp))
+		if err != nil {
+			return err
+		}
+		mb.ContinuationTimestamps = append(mb.ContinuationTimestamps, timestamp)
+	}
+
+	for len(mb.ContinuationTimestamps) > len(pages)-1 {
+		last := len(mb.ContinuationTimestamps) - 1
+		if _, _, err := client.DeleteMessage(mb.ChannelID, mb.ContinuationTimestamps[last]); err != nil {
+			return err
+		}
+		mb.ContinuationTimestamps = mb.ContinuationTimestamps[:last]
+	}
+	return nil
+}
+
+// Clone returns a copy of the board which shares no blocks with it, to put the board back when saving it fails
+func (mb *MenuBoard) Clone() (*MenuBoard, error) {
+	var clone *MenuBoard
+	for i, page := range mb.ToPages() {
+		data, err := json.Marshal(slack.Blocks{BlockSet: page})
+		if err != nil {
+			return nil, err
+		}
+		var blocks slack.Blocks
+		if err := json.Unmarshal(data, &blocks); err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			clone = ParseMenuBlocks(blocks.BlockSet)
+			continue
+		}
+		clone.appendMenuBlocks(blocks.BlockSet[1:])
+	}
+	clone.ChannelID = mb.ChannelID
+	clone.Timestamp = mb.Timestamp
+	clone.ThreadTimestamp = mb.ThreadTimestamp
+	clone.ParentUserID = mb.ParentUserID
+	clone.ContinuationTimestamps = append([]string{}, mb.ContinuationTimestamps...)
+	return clone, nil
+}
+
+// ToPages splits the board into message sized block lists, the first one is the board message
+func (mb *MenuBoard) ToPages() [][]slack.Block {
+	menuGroups := [][]slack.Block{{}}
+	budget := maxBlocksPerMessage - len(mb.HeaderBlocks) - len(mb.TailBlocks)
+	for _, menu := range mb.Menus {
+		menuBlocks := menu.ToBlocks()
+		last := len(menuGroups) - 1
+		if len(menuGroups[last]) > 0 && len(menuGroups[last])+len(menuBlocks) > budget {
+			menuGroups = append(menuGroups, []slack.Block{})
+			last++
+			budget = maxBlocksPerMessage - 1
+		}
+		// A menu with more status blocks than a message holds is split over messages
+		for len(menuGroups[last])+len(menuBlocks) > budget && budget > len(menuGroups[last]) {
+			fit := budget - len(menuGroups[last])
+			menuGroups[last] = append(menuGroups[last], menuBlocks[:fit]...)
+			menuBlocks = menuBlocks[fit:]
+			menuGroups = append(menuGroups, []slack.Block{})
+			last++
+			budget = maxBlocksPerMessage - 1
+		}
+		menuGroups[last] = append(menuGroups[last], menuBlocks...)
+	}
+
+	boardPage := append([]slack.Block{}, mb.HeaderBlocks...)
+	boardPage = append(boardPage, menuGroups[0]...)
+	boardPage = append(boardPage, mb.TailBlocks...)
+	pages := [][]slack.Block{boardPage}
+	for _, menuGroup := range menuGroups[1:] {
+		pages = append(pages, append([]slack.Block{NewContinuationBlock(mb.Timestamp)}, menuGroup...))
+	}
+	return pages
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestToPages(t *testing.T) {
+	tests := []struct {
+		name     string
+		menus    int
+		choosers int
+	}{
+		{name: "small board", menus: 3, choosers: 5},
+		{name: "many menus", menus: 60, choosers: 1},
+		{name: "menu with more choosers than a message holds", menus: 1, choosers: 600},
+		{name: "large menus after small ones", menus: 3, choosers: 300},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			menuBoard := NewMenuBoard("점심", "U1")
+			for i := 0; i < test.menus; i++ {
+				menuName := fmt.Sprintf("메뉴%d", i)
+				menuBoard.AddMenu(menuName, "", "U1")
+				for j := 0; j < test.choosers; j++ {
+					menuBoard.ToggleMenuByUser(&slack.UserProfile{RealName: fmt.Sprintf("사람%d", j)}, menuName)
+				}
+			}
+
+			pages := menuBoard.ToPages()
+			for i, page := range pages {
+				if len(page) > maxBlocksPerMessage {
+					t.Errorf("page %d has %d blocks, want at most %d", i, len(page), maxBlocksPerMessage)
+				}
+			}
+
+			parsed := ParseMenuBlocks(pages[0])
+			for _, page := range pages[1:] {
+				parsed.appendMenuBlocks(page[1:])
+			}
+			if len(parsed.Menus) != test.menus {
+				t.Fatalf("parsed %d menus, want %d", len(parsed.Menus), test.menus)
+			}
+			for _, menu := range parsed.Menus {
+				if got := len(menu.GetChoosers()); got != test.choosers {
+					t.Errorf("%s has %d choosers, want %d", menu.MenuName, got, test.choosers)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,521 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+const defaultBoardTitle = "Menu"
+const numHistoryBoards = 5
+const numStatsRanks = 5
+
+// CommandContext is where and by whom a command is issued, from slash command or app mention
+type CommandContext struct {
+	Handler         *Handler
+	ChannelID       string
+	ThreadTimestamp string
+	UserID          string
+	TriggerID       string
+	// Reply sends text to the channel, or only to the user when ephemeral
+	Reply func(text string, ephemeral bool) error
+}
+
+// Command is a subcommand of /waiter which app mentions share
+type Command struct {
+	Name        string
+	Usage       string
+	Description string
+	Run         func(ctx *CommandContext, args string) error
+}
+
+// CommandRouter routes command text to the subcommand named by its first word
+type CommandRouter struct {
+	commands []*Command
+}
+
+// NewCommandRouter creates router with the waiter subcommands
+func NewCommandRouter() *CommandRouter {
+	router := &CommandRouter{}
+	router.Register(&Command{Name: "start", Usage: "start [제목]", Description: "메뉴판을 연다옹", Run: StartCommand})
+	router.Register(&Command{Name: "new", Usage: "new [제목]", Description: "이미 메뉴판이 있는 스레드에도 메뉴판을 하나 더 연다옹", Run: NewCommand})
+	router.Register(&Command{Name: "cohost", Usage: "cohost @사람", Description: "메뉴판 스레드에서 멘션으로 부르면 메뉴판을 같이 열 사람을 정한다옹", Run: CohostCommand})
+	router.Register(&Command{Name: "guest", Usage: "guest <이름> [@사람]", Description: "메뉴판 스레드에서 멘션으로 부르면 슬랙 계정이 없는 손님을 추가하고, 손님 몫은 멘션한 사람이 낸다옹", Run: GuestCommand})
+	router.Register(&Command{Name: "trash", Usage: "trash", Description: "메뉴판 스레드에서 멘션으로 부르면 지운 메뉴를 보여주고 되살린다옹", Run: TrashCommand})
+	router.Register(&Command{Name: "undo", Usage: "undo", Description: "메뉴판 스레드에서 멘션으로 부르면 마지막 변경을 되돌린다옹", Run: UndoCommand})
+	router.Register(&Command{Name: "diet", Usage: "diet [<식단> <식단> | off]", Description: "내 식단을 보거나 정하고, 맞지 않는 메뉴를 고르면 알려준다옹", Run: DietCommand})
+	router.Register(&Command{Name: "history", Usage: "history", Description: "이 채널의 지난 주문을 보여준다옹", Run: HistoryCommand})
+	router.Register(&Command{Name: "templates", Usage: "templates [save <이름> <메뉴>, <메뉴> | delete <이름>]", Description: "메뉴 템플릿을 보거나 저장/삭제한다옹", Run: TemplatesCommand})
+	router.Register(&Command{Name: "stats", Usage: "stats", Description: "이 채널의 인기 메뉴와 단골을 보여준다옹", Run: StatsCommand})
+	router.Register(&Command{Name: "config", Usage: "config [title <제목> | quote on/off | reactions on/off | delete anyone/creator/host]", Description: "이 채널의 설정을 보거나 바꾼다옹", Run: ConfigCommand})
+	router.Register(&Command{Name: "help", Usage: "help", Description: "도움말을 보여준다옹", Run: func(ctx *CommandContext, args string) error {
+		return ctx.Reply(router.Help(), true)
+	}})
+	return router
+}
+
+// Register adds the subcommand
+func (cr *CommandRouter) Register(command *Command) {
+	cr.commands = append(cr.commands, command)
+}
+
+// Lookup returns the subcommand of the name
+func (cr *CommandRouter) Lookup(name string) (*Command, bool) {
+	for _, command := range cr.commands {
+		if command.Name == strings.ToLower(name) {
+			return command, true
+		}
+	}
+	return nil, false
+}
+
+// Route runs the subcommand of the text, replying help for unknown subcommands
+func (cr *CommandRouter) Route(ctx *CommandContext, text string) error {
+	name, args := SplitCommand(text)
+	if name == "" {
+		name = "help"
+	}
+	command, ok := cr.Lookup(name)
+	if !ok {
+		return ctx.Reply(fmt.Sprintf("`%s`는 모르는 명령이다옹\n\n%s", name, cr.Help()), true)
+	}
+	return command.Run(ctx, args)
+}
+
+// Help returns usages of the subcommands
+func (cr *CommandRouter) Help() string {
+	help := "*Waiter Bot 사용법*\n"
+	for _, command := range cr.commands {
+		help += fmt.Sprintf("• `/waiter %s` %s\n", command.Usage, command.Description)
+	}
+	help += "\n" + MentionUsage
+	return help
+}
+
+// SplitCommand splits the first word and the rest of the text
+func SplitCommand(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexAny(text, " \t\n"); i >= 0 {
+		return text[:i], strings.TrimSpace(text[i:])
+	}
+	return text, ""
+}
+
+// RespondToURL replies to slash command through its response url
+func RespondToURL(responseURL string, text string, ephemeral bool) error {
+	responseType := "in_channel"
+	if ephemeral {
+		responseType = "ephemeral"
+	}
+	body, err := json.Marshal(map[string]string{"response_type": responseType, "text": text})
+	if err != nil {
+		return err
+	}
+
+	response, err := http.Post(responseURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("response url returned %s", response.Status)
+	}
+	return nil
+}
+
+// StartCommand opens a menu board with the title
+func StartCommand(ctx *CommandContext, args string) error {
+	title := strings.TrimSpace(args)
+	if title == "" {
+		title = ChannelBoardTitle(ctx.Handler, ctx.ChannelID)
+	}
+
+	err := StartBoard(ctx.Handler, ctx.ChannelID, ctx.ThreadTimestamp, NewChannelMenuBoard(ctx.Handler, ctx.ChannelID, title, ctx.UserID))
+	if err == ErrBoardAlreadyExists {
+		return ctx.Reply("이 스레드에는 이미 메뉴판이 있다옹", true)
+	}
+	return err
+}
+
+// NewCommand opens an additional menu board even if the thread already has one
+func NewCommand(ctx *CommandContext, args string) error {
+	title := strings.TrimSpace(args)
+	if title == "" {
+		title = ChannelBoardTitle(ctx.Handler, ctx.ChannelID)
+	}
+	return PostNewBoard(ctx.Handler, ctx.ChannelID, ctx.ThreadTimestamp, NewChannelMenuBoard(ctx.Handler, ctx.ChannelID, title, ctx.UserID))
+}
+
+// CohostCommand lets the mentioned users host the board of the thread together
+func CohostCommand(ctx *CommandContext, args string) error {
+	if ctx.ThreadTimestamp == "" {
+		return ctx.Reply("같이 여는 사람은 메뉴판 스레드에서 `@Waiter Bot cohost @사람` 처럼 정해달라옹", true)
+	}
+	userIDs := []string{}
+	for _, matches := range mentionPattern.FindAllStringSubmatch(args, -1) {
+		userIDs = append(userIDs, matches[1])
+	}
+	if len(userIDs) == 0 {
+		return ctx.Reply("같이 열 사람을 `@Waiter Bot cohost @사람` 처럼 멘션해달라옹", true)
+	}
+
+	messageUpdateMutex.Lock()
+	defer messageUpdateMutex.Unlock()
+
+	menuBoard, err := FindThreadMenuBoard(ctx.Handler, ctx.ChannelID, ctx.ThreadTimestamp)
+	if err == ErrMenuBoardNotFound {
+		return ctx.Reply("이 스레드에는 메뉴판이 없다옹", true)
+	}
+	if err != nil {
+		return err
+	}
+	if menuBoard.IsTerminated() {
+		return ctx.Reply("이미 마감된 메뉴판이다옹", true)
+	}
+	canManage, err := CanManageBoard(ctx.Handler, menuBoard, ctx.UserID)
+	if err != nil {
+		return err
+	}
+	if !canManage {
+		return ctx.Reply("같이 여는 사람은 메뉴판을 연 사람이나 같이 여는 사람만 정할 수 있다옹", true)
+	}
+
+	for _, userID := range userIDs {
+		if !menuBoard.IsHost(userID) && len(menuBoard.CohostUserIDs) >= maxCohosts {
+			return ctx.Reply(fmt.Sprintf("같이 여는 사람은 %d명까지다옹", maxCohosts), true)
+		}
+		menuBoard.AddCohost(userID)
+	}
+	if err := SaveOpenBoard(ctx.Handler, menuBoard); err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := AuditBoard(ctx.Handler, menuBoard, ctx.UserID, AuditCohost, "", userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GuestCommand adds a guest without slack account to the board of the thread, sponsored by the mentioned user or the caller
+func GuestCommand(ctx *CommandContext, args string) error {
+	if ctx.ThreadTimestamp == "" {
+		return ctx.Reply("손님은 메뉴판 스레드에서 `@Waiter Bot guest 이름 @사람` 처럼 추가해달라옹", true)
+	}
+	sponsorUserID := ctx.UserID
+	if matches := mentionPattern.FindStringSubmatch(args); matches != nil {
+		sponsorUserID = matches[1]
+	}
+	name := strings.Join(strings.Fields(mentionPattern.ReplaceAllString(args, "")), " ")
+	if name == "" {
+		return ctx.Reply("손님 이름을 `@Waiter Bot guest 이름 @사람` 처럼 적어달라옹", true)
+	}
+	if strings.Contains(name, ",") || utf8.RuneCountInString(name) > maxGuestNameLength {
+		return ctx.Reply(fmt.Sprintf("손님 이름은 쉼표 없이 %d자까지다옹", maxGuestNameLength), true)
+	}
+
+	messageUpdateMutex.Lock()
+	defer messageUpdateMutex.Unlock()
+
+	menuBoard, err := FindThreadMenuBoard(ctx.Handler, ctx.ChannelID, ctx.ThreadTimestamp)
+	if err == ErrMenuBoardNotFound {
+		return ctx.Reply("이 스레드에는 메뉴판이 없다옹", true)
+	}
+	if err != nil {
+		return err
+	}
+	if menuBoard.IsTerminated() {
+		return ctx.Reply("이미 마감된 메뉴판이다옹", true)
+	}
+	canManage, err := CanManageBoard(ctx.Handler, menuBoard, ctx.UserID)
+	if err != nil {
+		return err
+	}
+	if !canManage {
+		return ctx.Reply("손님은 메뉴판을 연 사람이나 같이 여는 사람만 추가할 수 있다옹", true)
+	}
+
+	if _, ok := menuBoard.FindGuest(name); ok {
+		return ctx.Reply(fmt.Sprintf("*%s*님은 이미 손님이다옹", name), true)
+	}
+	if len(menuBoard.Guests) >= maxGuests {
+		return ctx.Reply(fmt.Sprintf("손님은 %d명까지다옹", maxGuests), true)
+	}
+	numSponsored := 0
+	for _, guest := range menuBoard.Guests {
+		if guest.SponsorUserID == sponsorUserID {
+			numSponsored++
+		}
+	}
+	if numSponsored >= maxGuestsPerSponsor {
+		return ctx.Reply(fmt.Sprintf("한 사람이 내는 손님은 %d명까지다옹", maxGuestsPerSponsor), true)
+	}
+
+	guest := Guest{Name: name, SponsorUserID: sponsorUserID}
+	menuBoard.AddGuest(guest)
+	if err := SaveOpenBoard(ctx.Handler, menuBoard); err != nil {
+		return err
+	}
+	if err := AuditGuestAdd(ctx.Handler, menuBoard, ctx.UserID, guest); err != nil {
+		return err
+	}
+	return ctx.Reply(fmt.Sprintf("손님 *%s*님이 왔다옹. 👥 버튼으로 메뉴를 골라달라옹", name), true)
+}
+
+// TrashCommand shows deleted menus of the board of the thread with restore buttons
+func TrashCommand(ctx *CommandContext, args string) error {
+	if ctx.ThreadTimestamp == "" {
+		return ctx.Reply("지운 메뉴는 메뉴판 스레드에서 `@Waiter Bot trash` 처럼 불러서 봐달라옹", true)
+	}
+	menuBoard, err := FindThreadMenuBoard(ctx.Handler, ctx.ChannelID, ctx.ThreadTimestamp)
+	if err == ErrMenuBoardNotFound {
+		return ctx.Reply("이 스레드에는 메뉴판이 없다옹", true)
+	}
+	if err != nil {
+		return err
+	}
+	canManage, err := CanManageBoard(ctx.Handler, menuBoard, ctx.UserID)
+	if err != nil {
+		return err
+	}
+	if !canManage {
+		return ctx.Reply("지운 메뉴는 메뉴판을 연 사람이나 같이 여는 사람만 볼 수 있다옹", true)
+	}
+
+	trash := ctx.Handler.Store.TrashedMenus(ctx.Handler.TeamID, menuBoard.ChannelID, menuBoard.Timestamp)
+	if len(trash) == 0 {
+		return ctx.Reply(fmt.Sprintf("최근 %d분 동안 지운 메뉴가 없다옹", int(trashTTL.Minutes())), true)
+	}
+	blocks := []slack.Block{}
+	for _, trashed := range trash {
+		if len(blocks) >= maxBlocksPerMessage {
+			break
+		}
+		blocks = append(blocks, NewTrashedMenuBlock(ctx.Handler, trashed))
+	}
+	_, err = ctx.Handler.Client.PostEphemeral(ctx.ChannelID, ctx.UserID, slack.MsgOptionText("지운 메뉴", false), slack.MsgOptionBlocks(blocks...), slack.MsgOptionTS(ctx.ThreadTimestamp))
+	return err
+}
+
+// ChannelBoardTitle returns the board title configured in the channel
+func ChannelBoardTitle(handler *Handler, channelID string) string {
+	if title := handler.Store.ChannelConfig(handler.TeamID, channelID).Title; title != "" {
+		return title
+	}
+	return defaultBoardTitle
+}
+
+// NewChannelMenuBoard makes empty menu board in the mode configured in the channel
+func NewChannelMenuBoard(handler *Handler, channelID string, title string, hostUserID string) *MenuBoard {
+	menuBoard := NewMenuBoard(title, hostUserID)
+	if handler.Store.ChannelConfig(handler.TeamID, channelID).ReactionMode {
+		menuBoard.EnableReactionMode()
+	}
+	return menuBoard
+}
+
+// HistoryCommand replies the latest terminated boards of the channel
+func HistoryCommand(ctx *CommandContext, args string) error {
+	records := ctx.Handler.Store.ChannelBoards(ctx.Handler.TeamID, ctx.ChannelID)
+	if len(records) == 0 {
+		return ctx.Reply("아직 이 채널에서 마감된 주문이 없다옹", true)
+	}
+	if len(records) > numHistoryBoards {
+		records = records[:numHistoryBoards]
+	}
+
+	text := "*지난 주문*\n"
+	for _, record := range records {
+		numChoosers := 0
+		for _, menu := range record.Menus {
+			numChoosers += len(menu.Choosers)
+		}
+		text += fmt.Sprintf("• %s *%s* (<@%s>) 메뉴 %d개, %d그릇\n", ctx.Handler.InLocation(record.TerminatedAt).Format("01/02 15:04"), record.Title, record.HostUserID, len(record.Menus), numChoosers)
+		for _, menu := range record.Menus {
+			text += fmt.Sprintf(">%s ×%d\n", menu.MenuName, len(menu.Choosers))
+		}
+	}
+	return ctx.Reply(text, true)
+}
+
+// TemplatesCommand lists menu templates, or saves or deletes them for users who can configure the channel
+func TemplatesCommand(ctx *CommandContext, args string) error {
+	store := ctx.Handler.Store
+	action, rest := SplitCommand(args)
+	if action == "save" || action == "delete" {
+		canConfigure, err := CanConfigureChannel(ctx.Handler, ctx.ChannelID, ctx.UserID)
+		if err != nil {
+			return err
+		}
+		if !canConfigure {
+			return ctx.Reply("이 채널에 다른 사람이 연 메뉴판이 있어서 템플릿은 그 메뉴판을 연 사람이나 같이 여는 사람만 바꿀 수 있다옹", true)
+		}
+	}
+
+	switch action {
+	case "":
+		names := store.Templates(ctx.Handler.TeamID)
+		if len(names) == 0 {
+			return ctx.Reply("저장된 템플릿이 없다옹. `/waiter templates save 중국집 짜장면, 짬뽕` 처럼 저장해달라옹", true)
+		}
+		text := "*메뉴 템플릿*\n"
+		for _, name := range names {
+			menus, _ := store.Template(ctx.Handler.TeamID, name)
+			text += fmt.Sprintf("• *%s* %s\n", name, strings.Join(menus, ", "))
+		}
+		return ctx.Reply(text, true)
+
+	case "save":
+		name, menuText := SplitCommand(rest)
+		menus := SplitMenuNames(menuText)
+		if name == "" || len(menus) == 0 {
+			return ctx.Reply("`/waiter templates save <이름> <메뉴>, <메뉴>` 처럼 적어달라옹", true)
+		}
+		menuBoard := NewMenuBoard("", "")
+		for _, menuName := range menus {
+			if errorMessage := menuBoard.ValidateMenuName(menuName, ctx.UserID); errorMessage != "" {
+				return ctx.Reply(fmt.Sprintf("%s: %s", menuName, errorMessage), true)
+			}
+			menuBoard.AddMenu(menuName, "", "")
+		}
+		if err := store.SaveTemplate(ctx.Handler.TeamID, name, menus); err != nil {
+			return err
+		}
+		return ctx.Reply(fmt.Sprintf("템플릿 *%s*를 저장했다옹", name), true)
+
+	case "delete":
+		ok, err := store.DeleteTemplate(ctx.Handler.TeamID, rest)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ctx.Reply(fmt.Sprintf("템플릿 *%s*는 없다옹", rest), true)
+		}
+		return ctx.Reply(fmt.Sprintf("템플릿 *%s*를 지웠다옹", rest), true)
+	}
+	return ctx.Reply("`/waiter templates [save <이름> <메뉴>, <메뉴> | delete <이름>]` 처럼 적어달라옹", true)
+}
+
+// StatsCommand replies the popular menus and regulars of the channel
+func StatsCommand(ctx *CommandContext, args string) error {
+	records := ctx.Handler.Store.ChannelBoards(ctx.Handler.TeamID, ctx.ChannelID)
+	if len(records) == 0 {
+		return ctx.Reply("아직 이 채널에서 마감된 주문이 없다옹", true)
+	}
+
+	menuCounts := map[string]int{}
+	chooserCounts := map[string]int{}
+	for _, record := range records {
+		for _, menu := range record.Menus {
+			menuCounts[menu.MenuName] += len(menu.Choosers)
+			for _, chooser := range menu.Choosers {
+				chooserCounts[chooser]++
+			}
+		}
+	}
+
+	text := fmt.Sprintf("*이 채널에서 마감된 주문 %d번*\n\n*인기 메뉴*\n", len(records))
+	for i, name := range rankKeys(menuCounts, numStatsRanks) {
+		text += fmt.Sprintf("%d. %s ×%d\n", i+1, name, menuCounts[name])
+	}
+	text += "\n*단골*\n"
+	for i, name := range rankKeys(chooserCounts, numStatsRanks) {
+		text += fmt.Sprintf("%d. %s ×%d\n", i+1, name, chooserCounts[name])
+	}
+	return ctx.Reply(text, false)
+}
+
+// ConfigCommand shows or changes the config of the channel
+func ConfigCommand(ctx *CommandContext, args string) error {
+	store := ctx.Handler.Store
+	config := store.ChannelConfig(ctx.Handler.TeamID, ctx.ChannelID)
+	key, value := SplitCommand(args)
+	switch key {
+	case "":
+		title := config.Title
+		if title == "" {
+			title = defaultBoardTitle
+		}
+		quote, reactions, deletePolicy := "on", "off", config.DeletePolicy
+		if deletePolicy == "" {
+			deletePolicy = DeletePolicyCreator
+		}
+		if config.NoQuote {
+			quote = "off"
+		}
+		if config.ReactionMode {
+			reactions = "on"
+		}
+		return ctx.Reply(fmt.Sprintf("*이 채널의 설정*\n• title: %s\n• quote: %s\n• reactions: %s\n• delete: %s", title, quote, reactions, deletePolicy), true)
+	}
+
+	canConfigure, err := CanConfigureChannel(ctx.Handler, ctx.ChannelID, ctx.UserID)
+	if err != nil {
+		return err
+	}
+	if !canConfigure {
+		return ctx.Reply("이 채널에 다른 사람이 연 메뉴판이 있어서 설정은 그 메뉴판을 연 사람이나 같이 여는 사람만 바꿀 수 있다옹", true)
+	}
+
+	switch key {
+	case "title":
+		config.Title = value
+	case "quote":
+		if value != "on" && value != "off" {
+			return ctx.Reply("quote는 on 이나 off 로 적어달라옹", true)
+		}
+		config.NoQuote = value == "off"
+	case "reactions":
+		if value != "on" && value != "off" {
+			return ctx.Reply("reactions는 on 이나 off 로 적어달라옹", true)
+		}
+		config.ReactionMode = value == "on"
+	case "delete":
+		if value != DeletePolicyAnyone && value != DeletePolicyCreator && value != DeletePolicyHost {
+			return ctx.Reply("delete는 anyone, creator, host 중에 적어달라옹", true)
+		}
+		config.DeletePolicy = value
+	default:
+		return ctx.Reply(fmt.Sprintf("`%s`는 모르는 설정이다옹", key), true)
+	}
+
+	if err := store.SaveChannelConfig(ctx.Handler.TeamID, ctx.ChannelID, config); err != nil {
+		return err
+	}
+	return ctx.Reply(fmt.Sprintf("%s 설정을 바꿨다옹", key), true)
+}
+
+// SplitMenuNames splits comma separated menu names
+func SplitMenuNames(text string) []string {
+	menus := []string{}
+	for _, menuName := range strings.Split(text, ",") {
+		if menuName = strings.TrimSpace(menuName); menuName != "" {
+			menus = append(menus, menuName)
+		}
+	}
+	return menus
+}
+
+// rankKeys returns keys of the largest counts
+func rankKeys(counts map[string]int, limit int) []string {
+	keys := []string{}
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slack-waiter-bot/ids"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		text     string
+		wantName string
+		wantArgs string
+	}{
+		{text: "", wantName: "", wantArgs: ""},
+		{text: "help", wantName: "help", wantArgs: ""},
+		{text: "  templates  save 중국집 짜장면, 짬뽕 ", wantName: "templates", wantArgs: "save 중국집 짜장면, 짬뽕"},
+		{text: "config\ttitle 점심", wantName: "config", wantArgs: "title 점심"},
+		{text: "guest\n김손님", wantName: "guest", wantArgs: "김손님"},
+	}
+	for _, test := range tests {
+		name, args := SplitCommand(test.text)
+		if name != test.wantName || args != test.wantArgs {
+			t.Errorf("SplitCommand(%q) = %q, %q, want %q, %q", test.text, name, args, test.wantName, test.wantArgs)
+		}
+	}
+}
+
+func TestCommandRouterRoute(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+	fake.AddUser("U2", "손님")
+	// U1 hosts an open board of the channel, which the templates of others must not change under
+	menuBoard := NewMenuBoard("점심", "U1")
+	if err := PostNewBoard(handler, "C1", "", menuBoard); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		userID       string
+		text         string
+		wantReply    string
+		wantTemplate []string
+	}{
+		{name: "empty text", userID: "U2", text: "", wantReply: "*Waiter Bot 사용법*"},
+		{name: "help", userID: "U2", text: "help", wantReply: "*Waiter Bot 사용법*"},
+		{name: "upper case", userID: "U2", text: "HELP", wantReply: "*Waiter Bot 사용법*"},
+		{name: "unknown", userID: "U2", text: "order 짜장면", wantReply: "`order`는 모르는 명령이다옹"},
+		{name: "save by other", userID: "U2", text: "templates save 중국집 짜장면, 짬뽕", wantReply: "템플릿은 그 메뉴판을 연 사람이나 같이 여는 사람만 바꿀 수 있다옹"},
+		{name: "save by host", userID: "U1", text: "templates save 중국집 짜장면, 짬뽕", wantReply: "템플릿 *중국집*를 저장했다옹", wantTemplate: []string{"짜장면", "짬뽕"}},
+		{name: "list by other", userID: "U2", text: "templates", wantReply: "• *중국집* 짜장면, 짬뽕", wantTemplate: []string{"짜장면", "짬뽕"}},
+		{name: "delete by other", userID: "U2", text: "templates delete 중국집", wantReply: "템플릿은 그 메뉴판을 연 사람이나 같이 여는 사람만 바꿀 수 있다옹", wantTemplate: []string{"짜장면", "짬뽕"}},
+		{name: "delete by host", userID: "U1", text: "templates delete 중국집", wantReply: "템플릿 *중국집*를 지웠다옹"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replies := []string{}
+			ctx := &CommandContext{
+				Handler:   handler,
+				ChannelID: "C1",
+				UserID:    test.userID,
+				Reply: func(text string, ephemeral bool) error {
+					replies = append(replies, text)
+					return nil
+				},
+			}
+			if err := handler.Commands.Route(ctx, test.text); err != nil {
+				t.Fatal(err)
+			}
+			if len(replies) != 1 || !strings.Contains(replies[0], test.wantReply) {
+				t.Errorf("replies = %q, want %q", replies, test.wantReply)
+			}
+			if menus, _ := handler.Store.Template("T1", "중국집"); strings.Join(menus, ",") != strings.Join(test.wantTemplate, ",") {
+				t.Errorf("template = %q, want %q", menus, test.wantTemplate)
+			}
+		})
+	}
+}
+
+func TestCommandTransports(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+
+	// Slash commands reply through the response url
+	responses := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		responses <- string(body)
+	}))
+	defer server.Close()
+	handler.DispatchCommand(slack.SlashCommand{Command: "/waiter", Text: "help", TeamID: "T1", ChannelID: "C1", UserID: "U1", ResponseURL: server.URL})
+	select {
+	case response := <-responses:
+		if !strings.Contains(response, `"response_type":"ephemeral"`) || !strings.Contains(response, "Waiter Bot 사용법") {
+			t.Errorf("response = %s, want help only to the user", response)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("slash command is not answered")
+	}
+
+	// Mentions route subcommands the same way, replying in the thread
+	mention := func(text string) string {
+		timestamp := fake.PostUserMessage("C1", "U1", text, "")
+		event := &slackevents.AppMentionEvent{Channel: "C1", User: "U1", Text: text, TimeStamp: timestamp}
+		if err := HandleAppMentionEvent(event, handler); err != nil {
+			t.Fatal(err)
+		}
+		return timestamp
+	}
+	mention("<@UBOT> help")
+	ephemerals := fake.Ephemerals()
+	if len(ephemerals) != 1 || !strings.Contains(ephemerals[0].Text, "Waiter Bot 사용법") {
+		t.Errorf("ephemerals = %+v, want help only to the user", ephemerals)
+	}
+
+	// Text which is not a subcommand is parsed as menus
+	timestamp := mention("<@UBOT> 짜장면, 짬뽕")
+	menuBoard, err := FindThreadMenuBoard(handler, "C1", timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, menu := range menuBoard.Menus {
+		names = append(names, menu.MenuName)
+	}
+	if !reflect.DeepEqual(names, []string{"짜장면", "짬뽕"}) {
+		t.Errorf("menus = %q, want the menus of the mention", names)
+	}
+}
+
+func TestGuestCommand(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+	fake.AddUser("U2", "김철수")
+	fake.AddUser("U3", "박영희")
+	mention := func(userID string, text string, threadTimestamp string) string {
+		timestamp := fake.PostUserMessage("C1", userID, text, threadTimestamp)
+		event := &slackevents.AppMentionEvent{Channel: "C1", User: userID, Text: text, TimeStamp: timestamp, ThreadTimeStamp: threadTimestamp}
+		if err := HandleAppMentionEvent(event, handler); err != nil {
+			t.Fatal(err)
+		}
+		return timestamp
+	}
+	lastEphemeral := func(userID string) string {
+		ephemerals := fake.Ephemerals()
+		for i := len(ephemerals) - 1; i >= 0; i-- {
+			if ephemerals[i].UserID == userID {
+				return ephemerals[i].Text
+			}
+		}
+		return ""
+	}
+
+	threadTimestamp := mention("U1", "<@UBOT> 짜장면, 짬뽕", "")
+	menuBoard, err := FindThreadMenuBoard(handler, "C1", threadTimestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the hosts add guests, sponsored by the mentioned member
+	mention("U3", "<@UBOT> guest 인턴 <@U2>", threadTimestamp)
+	if reply := lastEphemeral("U3"); !strings.Contains(reply, "메뉴판을 연 사람이나 같이 여는 사람만 추가할 수 있다옹") {
+		t.Errorf("reply = %q, want refusal to others", reply)
+	}
+	mention("U1", "<@UBOT> guest 인턴 <@U2>", threadTimestamp)
+	if reply := lastEphemeral("U1"); !strings.Contains(reply, "손님 *인턴*님이 왔다옹") {
+		t.Errorf("reply = %q, want the guest added", reply)
+	}
+	mention("U1", "<@UBOT> guest 인턴", threadTimestamp)
+	if reply := lastEphemeral("U1"); !strings.Contains(reply, "이미 손님이다옹") {
+		t.Errorf("reply = %q, want the duplicate guest refused", reply)
+	}
+	loaded, err := LoadMenuBoard(fake, "C1", menuBoard.Timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Guests, []Guest{{Name: "인턴", SponsorUserID: "U2"}}) {
+		t.Fatalf("guests = %+v, want the guest sponsored by U2", loaded.Guests)
+	}
+
+	// The guest chooses the menu through the order for others view, and is paid by the sponsor on close
+	sendAction(t, handler, blockAction("U1", menuBoard.Timestamp, ids.OrderForOther, ids.OrderForOther))
+	waitFor(t, "the order for others view opened", func() bool { return len(fake.Views()) == 1 })
+	view := fake.Views()[0]
+	submission := fmt.Sprintf(`{"type":"view_submission","trigger_id":"U1/guest","team":{"id":"T1"},"user":{"id":"U1"},"view":{"callback_id":"%s","private_metadata":%q,"state":{"values":{
+		"%s":{"%s":{"type":"external_select","selected_option":{"value":"짜장면"}}},
+		"%s":{"%s":{"type":"multi_static_select","selected_options":[{"value":"인턴"}]}},
+		"%s":{"%s":{"type":"radio_buttons","selected_option":{"value":"%s"}}}}}}}`,
+		view.CallbackID, view.PrivateMetadata,
+		ids.SubmitMenuInputBlock, ids.SubmitMenuInput,
+		ids.OrderGuestsBlock, ids.OrderGuests,
+		ids.OrderModeBlock, ids.OrderMode, OrderModeAdd)
+	var response slack.ViewSubmissionResponse
+	if err := json.Unmarshal([]byte(sendAction(t, handler, submission)), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.View == nil {
+		t.Fatalf("response = %+v, want the preview", response)
+	}
+	sendAction(t, handler, confirmSubmission("U1", response.View))
+	waitFor(t, "the guest chose", func() bool {
+		loaded, err := LoadMenuBoard(fake, "C1", menuBoard.Timestamp)
+		return err == nil && reflect.DeepEqual(loaded.Menus[0].GetChoosers(), []string{"인턴 (손님)"})
+	})
+	sendAction(t, handler, blockAction("U1", menuBoard.Timestamp, ids.TerminateMenu, ids.TerminateMenu))
+	waitFor(t, "the board closed", func() bool { return len(handler.Store.ChannelBoards("T1", "C1")) == 1 })
+	message, err := fake.Message("C1", menuBoard.Timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := ""
+	for _, block := range message.Blocks.BlockSet {
+		if section, ok := block.(*slack.SectionBlock); ok {
+			summary += section.Text.Text
+		}
+	}
+	if !strings.Contains(summary, "<@U2>님이 내는 손님 몫") || !strings.Contains(summary, ">인턴 (손님): 짜장면") {
+		t.Errorf("summary = %s, want the guest under the sponsor", summary)
+	}
+
+	// Stats count the guest apart from members by the marked name
+	mention("U3", "<@UBOT> stats", "")
+	messages := fake.Messages("C1")
+	stats := messages[len(messages)-1]
+	if stats.User != "UBOT" || !strings.Contains(stats.Text, "1. 짜장면 ×1") || !strings.Contains(stats.Text, "1. 인턴 (손님) ×1") {
+		t.Errorf("stats = %q, want the menu and the guest counted", stats.Text)
+	}
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// Tags of menus telling what they contain
+const (
+	MenuTagPork    = "pork"
+	MenuTagBeef    = "beef"
+	MenuTagChicken = "chicken"
+	MenuTagSeafood = "seafood"
+	MenuTagNuts    = "nuts"
+	MenuTagDairy   = "dairy"
+	MenuTagEgg     = "egg"
+	MenuTagGluten  = "gluten"
+)
+
+// menuTags are the tags in the order they are shown
+var menuTags = []string{MenuTagPork, MenuTagBeef, MenuTagChicken, MenuTagSeafood, MenuTagNuts, MenuTagDairy, MenuTagEgg, MenuTagGluten}
+
+var menuTagNames = map[string]string{
+	MenuTagPork:    "돼지고기",
+	MenuTagBeef:    "소고기",
+	MenuTagChicken: "닭고기",
+	MenuTagSeafood: "해산물",
+	MenuTagNuts:    "견과류",
+	MenuTagDairy:   "유제품",
+	MenuTagEgg:     "달걀",
+	MenuTagGluten:  "밀가루",
+}
+
+// Dietary restrictions users keep
+const (
+	DietVegetarian = "vegetarian"
+	DietNoPork     = "no_pork"
+	DietNoBeef     = "no_beef"
+	DietSeafood    = "seafood_allergy"
+	DietNuts       = "nut_allergy"
+	DietDairy      = "no_dairy"
+	DietEgg        = "egg_allergy"
+	DietGluten     = "no_gluten"
+)
+
+// dietRestrictions are the restrictions in the order they are shown
+var dietRestrictions = []string{DietVegetarian, DietNoPork, DietNoBeef, DietSeafood, DietNuts, DietDairy, DietEgg, DietGluten}
+
+var dietRestrictionNames = map[string]string{
+	DietVegetarian: "채식",
+	DietNoPork:     "돼지고기 안 먹음",
+	DietNoBeef:     "소고기 안 먹음",
+	DietSeafood:    "해산물 알레르기",
+	DietNuts:       "견과류 알레르기",
+	DietDairy:      "유제품 안 먹음",
+	DietEgg:        "달걀 알레르기",
+	DietGluten:     "밀가루 안 먹음",
+}
+
+// dietConflicts are the menu tags each restriction avoids
+var dietConflicts = map[string][]string{
+	DietVegetarian: {MenuTagPork, MenuTagBeef, MenuTagChicken, MenuTagSeafood},
+	DietNoPork:     {MenuTagPork},
+	DietNoBeef:     {MenuTagBeef},
+	DietSeafood:    {MenuTagSeafood},
+	DietNuts:       {MenuTagNuts},
+	DietDairy:      {MenuTagDairy},
+	DietEgg:        {MenuTagEgg},
+	DietGluten:     {MenuTagGluten},
+}
+
+// ConflictingTags returns the tags of the menu which the restrictions avoid, in the order of menu tags
+func ConflictingTags(restrictions []string, tags []string) []string {
+	avoided := map[string]bool{}
+	for _, restriction := range restrictions {
+		for _, tag := range dietConflicts[restriction] {
+			avoided[tag] = true
+		}
+	}
+	conflicts := []string{}
+	for _, tag := range tags {
+		if avoided[tag] {
+			conflicts = append(conflicts, tag)
+		}
+	}
+	return conflicts
+}
+
+// menuTagLabel returns the names of the tags shown after the menu name, empty without tags
+func menuTagLabel(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return " [" + joinNames(tags, menuTagNames) + "]"
+}
+
+// joinNames joins names of the keys which are known
+func joinNames(keys []string, names map[string]string) string {
+	joined := []string{}
+	for _, key := range keys {
+		if name, ok := names[key]; ok {
+			joined = append(joined, name)
+		}
+	}
+	return strings.Join(joined, ", ")
+}
+
+// NewMenuTagOptions returns options of the menu tags
+func NewMenuTagOptions() []*slack.OptionBlockObject {
+	options := []*slack.OptionBlockObject{}
+	for _, tag := range menuTags {
+		options = append(options, slack.NewOptionBlockObject(tag, slack.NewTextBlockObject("plain_text", menuTagNames[tag], false, false), nil))
+	}
+	return options
+}
+
+// NewDietOptions returns options of the dietary restrictions
+func NewDietOptions() []*slack.OptionBlockObject {
+	options := []*slack.OptionBlockObject{}
+	for _, restriction := range dietRestrictions {
+		options = append(options, slack.NewOptionBlockObject(restriction, slack.NewTextBlockObject("plain_text", dietRestrictionNames[restriction], false, false), nil))
+	}
+	return options
+}
+
+// SaveDiet saves the dietary restrictions of the user
+func SaveDiet(handler *Handler, userID string, restrictions []string) error {
+	known := []string{}
+	for _, restriction := range dietRestrictions {
+		for _, value := range restrictions {
+			if value == restriction {
+				known = append(known, restriction)
+				break
+			}
+		}
+	}
+	return handler.Store.SaveDietProfile(handler.TeamID, userID, DietProfile{Restrictions: known})
+}
+
+// WarnDietConflict lets the user know ephemerally when the menu the user has chosen conflicts with the diet of the user
+func WarnDietConflict(handler *Handler, menuBoard *MenuBoard, userID string, menuName string) error {
+	menuIndex, ok := menuBoard.MenuNameIndexMap[menuName]
+	if !ok || len(menuBoard.Menus[menuIndex].Tags) == 0 {
+		return nil
+	}
+	restrictions := handler.Store.DietProfile(handler.TeamID, userID).Restrictions
+	conflicts := ConflictingTags(restrictions, menuBoard.Menus[menuIndex].Tags)
+	if len(conflicts) == 0 {
+		return nil
+	}
+	text := fmt.Sprintf("⚠️ *%s*에는 %s 성분이 있다옹. 내 식단(%s)과 맞지 않으니 확인해달라옹", menuName, joinNames(conflicts, menuTagNames), joinNames(restrictions, dietRestrictionNames))
+	return ReplyEphemeral(handler, menuBoard, userID, text)
+}
+
+// dietSummary returns choices of the board which conflict with the diets of the choosers, for the host to check before ordering
+func dietSummary(handler *Handler, menuBoard *MenuBoard) string {
+	// Boards keep the names of choosers, so the restrictions are matched by the current names of the users
+	restrictionsByName := map[string][]string{}
+	for userID, restrictions := range handler.Store.DietRestrictionsByUser(handler.TeamID) {
+		profile, err := handler.Profiles.GetProfile(userID)
+		if err != nil {
+			handler.Logger.Printf("[ERROR] Failed to get profile of %s: %v\n", userID, err)
+			continue
+		}
+		// Users of the same name share the warnings rather than missing them
+		restrictionsByName[profile.RealName] = append(restrictionsByName[profile.RealName], restrictions...)
+	}
+	lines := []string{}
+	for _, menu := range menuBoard.Menus {
+		if len(menu.Tags) == 0 {
+			continue
+		}
+		for _, chooser := range menu.GetChoosers() {
+			if conflicts := ConflictingTags(restrictionsByName[chooser], menu.Tags); len(conflicts) > 0 {
+				lines = append(lines, fmt.Sprintf(">%s: %s (%s)\n", chooser, menu.MenuName, joinNames(conflicts, menuTagNames)))
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	sort.Strings(lines)
+	return "⚠️ *식단과 맞지 않는 주문*\n" + strings.Join(lines, "")
+}
+
+// DietCommand shows or changes the dietary restrictions of the user
+func DietCommand(ctx *CommandContext, args string) error {
+	usage := "`/waiter diet <식단> <식단>` 처럼 고르거나 `/waiter diet off` 로 지운다옹\n"
+	for _, restriction := range dietRestrictions {
+		usage += fmt.Sprintf("• `%s` %s\n", restriction, dietRestrictionNames[restriction])
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		restrictions := ctx.Handler.Store.DietProfile(ctx.Handler.TeamID, ctx.UserID).Restrictions
+		if len(restrictions) == 0 {
+			return ctx.Reply("아직 정한 식단이 없다옹\n"+usage, true)
+		}
+		return ctx.Reply(fmt.Sprintf("내 식단: %s\n", joinNames(restrictions, dietRestrictionNames))+usage, true)
+	}
+	if len(fields) == 1 && fields[0] == "off" {
+		if err := SaveDiet(ctx.Handler, ctx.UserID, nil); err != nil {
+			return err
+		}
+		return ctx.Reply("식단을 지웠다옹", true)
+	}
+	for _, field := range fields {
+		if _, ok := dietRestrictionNames[field]; !ok {
+			return ctx.Reply(fmt.Sprintf("`%s`는 모르는 식단이다옹\n", field)+usage, true)
+		}
+	}
+	if err := SaveDiet(ctx.Handler, ctx.UserID, fields); err != nil {
+		return err
+	}
+	return ctx.Reply(fmt.Sprintf("식단을 정했다옹: %s\n맞지 않는 메뉴를 고르면 알려준다옹", joinNames(fields, dietRestrictionNames)), true)
+}
+
+// SaveDietFromHome handles when user changes the diet checkboxes of App Home
+func SaveDietFromHome(handler *Handler, payload *slack.InteractionCallback, selectedOptions []slack.OptionBlockObject) error {
+	restrictions := []string{}
+	for _, option := range selectedOptions {
+		restrictions = append(restrictions, option.Value)
+	}
+	return SaveDiet(handler, payload.User.ID, restrictions)
+}
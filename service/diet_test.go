@@ -0,0 +1,136 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"slack-waiter-bot/ids"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestConflictingTags(t *testing.T) {
+	tests := []struct {
+		restrictions []string
+		tags         []string
+		want         []string
+	}{
+		{restrictions: nil, tags: []string{MenuTagPork}, want: []string{}},
+		{restrictions: []string{DietNoPork}, tags: []string{MenuTagBeef, MenuTagPork}, want: []string{MenuTagPork}},
+		{restrictions: []string{DietVegetarian}, tags: []string{MenuTagChicken, MenuTagDairy, MenuTagSeafood}, want: []string{MenuTagChicken, MenuTagSeafood}},
+		{restrictions: []string{DietNuts, DietEgg}, tags: []string{MenuTagEgg, MenuTagNuts}, want: []string{MenuTagEgg, MenuTagNuts}},
+	}
+	for _, test := range tests {
+		if got := ConflictingTags(test.restrictions, test.tags); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ConflictingTags(%v, %v) = %v, want %v", test.restrictions, test.tags, got, test.want)
+		}
+	}
+}
+
+func TestDietSummary(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "김철수")
+	fake.AddUser("U2", "박영희")
+	if err := SaveDiet(handler, "U1", []string{DietNoPork}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveDiet(handler, "U2", []string{DietSeafood}); err != nil {
+		t.Fatal(err)
+	}
+	// Restrictions follow the user after the name is changed
+	fake.AddUser("U1", "김철수 (개발팀)")
+	handler.Profiles.Invalidate("U1")
+
+	menuBoard := NewMenuBoard("점심", "U1")
+	menuBoard.AddMenu("제육볶음", "", "U1")
+	menuBoard.SetMenuTags("제육볶음", []string{MenuTagPork})
+	menuBoard.AddMenu("짬뽕", "", "U1")
+	menuBoard.SetMenuTags("짬뽕", []string{MenuTagSeafood, MenuTagPork})
+	for _, name := range []string{"김철수 (개발팀)", "박영희"} {
+		menuBoard.ToggleMenuByUser(&slack.UserProfile{RealName: name}, "제육볶음")
+	}
+
+	summary := dietSummary(handler, menuBoard)
+	if !strings.Contains(summary, ">김철수 (개발팀): 제육볶음") {
+		t.Errorf("summary = %q, want the conflict of the renamed user", summary)
+	}
+	if strings.Contains(summary, "박영희") {
+		t.Errorf("summary = %q, want no conflict of the user without pork restriction", summary)
+	}
+}
+
+func TestDietCommand(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "김철수")
+	mention := func(text string) string {
+		numEphemerals := len(fake.Ephemerals())
+		timestamp := fake.PostUserMessage("C1", "U1", text, "")
+		if err := HandleAppMentionEvent(&slackevents.AppMentionEvent{Channel: "C1", User: "U1", Text: text, TimeStamp: timestamp}, handler); err != nil {
+			t.Fatal(err)
+		}
+		ephemerals := fake.Ephemerals()
+		if len(ephemerals) != numEphemerals+1 || ephemerals[numEphemerals].UserID != "U1" {
+			t.Fatalf("ephemerals = %+v, want one reply only to the user", ephemerals[numEphemerals:])
+		}
+		return ephemerals[numEphemerals].Text
+	}
+	restrictions := func() []string {
+		return handler.Store.DietProfile("T1", "U1").Restrictions
+	}
+
+	if reply := mention("<@UBOT> diet"); !strings.Contains(reply, "아직 정한 식단이 없다옹") {
+		t.Errorf("reply = %q, want no diet yet", reply)
+	}
+
+	// Unknown restrictions are refused without saving any of them
+	if reply := mention("<@UBOT> diet no_pork halal"); !strings.Contains(reply, "`halal`는 모르는 식단이다옹") {
+		t.Errorf("reply = %q, want the unknown restriction refused", reply)
+	}
+	if got := restrictions(); len(got) != 0 {
+		t.Errorf("restrictions = %v, want nothing saved", got)
+	}
+
+	if reply := mention("<@UBOT> diet seafood_allergy no_pork"); !strings.Contains(reply, "식단을 정했다옹: 해산물 알레르기, 돼지고기 안 먹음") {
+		t.Errorf("reply = %q, want the diet saved", reply)
+	}
+	if got := restrictions(); !reflect.DeepEqual(got, []string{DietNoPork, DietSeafood}) {
+		t.Errorf("restrictions = %v, want saved in the order of restrictions", got)
+	}
+	if reply := mention("<@UBOT> diet"); !strings.Contains(reply, "내 식단: 돼지고기 안 먹음, 해산물 알레르기") {
+		t.Errorf("reply = %q, want the saved diet", reply)
+	}
+
+	// Choosing the menu of conflicting tags warns only the user
+	menuBoard := NewMenuBoard("점심", "U1")
+	menuBoard.AddMenu("제육볶음", "", "U1")
+	menuBoard.SetMenuTags("제육볶음", []string{MenuTagPork})
+	menuBoard.AddMenu("짜장면", "", "U1")
+	if err := PostNewBoard(handler, "C1", "", menuBoard); err != nil {
+		t.Fatal(err)
+	}
+	payload := &slack.InteractionCallback{User: slack.User{ID: "U1"}}
+	payload.Channel.ID = "C1"
+	payload.Message.Timestamp = menuBoard.Timestamp
+	numEphemerals := len(fake.Ephemerals())
+	for _, menuName := range []string{"제육볶음", "짜장면"} {
+		if err := SelectMenuByUser(handler, payload, menuName); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ephemerals := fake.Ephemerals()[numEphemerals:]; len(ephemerals) != 1 || ephemerals[0].UserID != "U1" || !strings.Contains(ephemerals[0].Text, "⚠️ *제육볶음*에는 돼지고기 성분이 있다옹") {
+		t.Errorf("ephemerals = %+v, want the warning of the tagged menu only", ephemerals)
+	}
+
+	if reply := mention("<@UBOT> diet off"); !strings.Contains(reply, "식단을 지웠다옹") {
+		t.Errorf("reply = %q, want the diet cleared", reply)
+	}
+	if got := restrictions(); len(got) != 0 {
+		t.Errorf("restrictions = %v, want the diet cleared", got)
+	}
+
+	// The checkboxes of App Home save the diet as well
+	sendAction(t, handler, fmt.Sprintf(`{"type":"block_actions","trigger_id":"U1/home","team":{"id":"T1"},"user":{"id":"U1"},"actions":[{"type":"checkboxes","block_id":"diet","action_id":"%s","selected_options":[{"value":"%s"}]}]}`, ids.DietRestrictions, DietVegetarian))
+	waitFor(t, "the diet saved from home", func() bool { return reflect.DeepEqual(restrictions(), []string{DietVegetarian}) })
+}
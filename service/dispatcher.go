@@ -0,0 +1,69 @@
+package service
+
+import (
+	"log"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// Job is a unit of work which runs after slack request is acknowledged
+type Job struct {
+	Name string
+	Run  func() error
+}
+
+// Dispatcher runs jobs with bounded number of workers so that requests are acknowledged immediately
+type Dispatcher struct {
+	Logger  *log.Logger
+	jobs    chan Job
+	backlog int64
+}
+
+// NewDispatcher creates dispatcher and starts its workers
+func NewDispatcher(logger *log.Logger, numWorkers int, queueSize int) *Dispatcher {
+	dispatcher := &Dispatcher{
+		Logger: logger,
+		jobs:   make(chan Job, queueSize),
+	}
+	for i := 0; i < numWorkers; i++ {
+		go dispatcher.work()
+	}
+	return dispatcher
+}
+
+// Dispatch queues the job without blocking and returns false when the queue is full
+func (d *Dispatcher) Dispatch(name string, run func() error) bool {
+	atomic.AddInt64(&d.backlog, 1)
+	select {
+	case d.jobs <- Job{Name: name, Run: run}:
+		return true
+	default:
+		atomic.AddInt64(&d.backlog, -1)
+		d.Logger.Printf("[ERROR] Job queue is full, dropped %s\n", name)
+		return false
+	}
+}
+
+// Backlog returns the number of jobs queued or running
+func (d *Dispatcher) Backlog() int64 {
+	return atomic.LoadInt64(&d.backlog)
+}
+
+func (d *Dispatcher) work() {
+	for job := range d.jobs {
+		d.runJob(job)
+	}
+}
+
+func (d *Dispatcher) runJob(job Job) {
+	defer atomic.AddInt64(&d.backlog, -1)
+	defer func() {
+		if r := recover(); r != nil {
+			d.Logger.Printf("[ERROR] Job %s panicked: %v\n%s", job.Name, r, debug.Stack())
+		}
+	}()
+
+	if err := job.Run(); err != nil {
+		d.Logger.Printf("[ERROR] Job %s failed: %v\n", job.Name, err)
+	}
+}
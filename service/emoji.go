@@ -3,31 +3,57 @@ package service
 import (
 	"errors"
 	"math/rand"
+	"strings"
+	"sync"
 	"time"
-
-	"github.com/slack-go/slack"
 )
 
 const emojiKeepInterval = time.Hour * 24
 
-// EmojiManager manages emoji list
+// standardEmojiList is used for reaction mode when custom emoji of the workspace run out
+var standardEmojiList = []string{
+	":hamburger: ", ":pizza: ", ":ramen: ", ":sushi: ", ":curry: ", ":rice: ", ":bento: ", ":spaghetti: ",
+	":taco: ", ":burrito: ", ":fried_shrimp: ", ":dumpling: ", ":poultry_leg: ", ":meat_on_bone: ", ":stew: ", ":green_salad: ",
+	":sandwich: ", ":hotdog: ", ":fries: ", ":rice_ball: ", ":oden: ", ":cake: ", ":doughnut: ", ":coffee: ",
+}
+
+// EmojiManager manages emoji list, shared by the workers handling the workspace
 type EmojiManager struct {
-	Client        *slack.Client
+	Client        SlackAPI
 	EmojiList     []string
 	LastUpdatedAt time.Time
+	mutex         sync.RWMutex
 }
 
 // GetRandomEmoji is the function to get random emoji which is in the periodically updated EmojiList
 func (em *EmojiManager) GetRandomEmoji() (string, error) {
-	if em.LastUpdatedAt.IsZero() || time.Now().After(em.LastUpdatedAt.Add(emojiKeepInterval)) {
-		em.UpdateEmojiList()
+	emojiList := em.currentEmojiList()
+	if len(emojiList) <= 0 {
+		return "", errors.New("EmojiList Error")
 	}
 
-	if em.EmojiList == nil || len(em.EmojiList) <= 0 {
-		return "", errors.New("EmojiList Error")
+	return emojiList[rand.Intn(len(emojiList))], nil
+}
+
+// GetRandomEmojiExcept returns random emoji which is not in used, falling back to standard emoji
+func (em *EmojiManager) GetRandomEmojiExcept(used map[string]bool) (string, error) {
+	for _, emojiList := range [][]string{em.currentEmojiList(), standardEmojiList} {
+		candidates := []string{}
+		for _, emoji := range emojiList {
+			if !used[EmojiName(emoji)] {
+				candidates = append(candidates, emoji)
+			}
+		}
+		if len(candidates) > 0 {
+			return candidates[rand.Intn(len(candidates))], nil
+		}
 	}
+	return "", errors.New("EmojiList Error")
+}
 
-	return em.EmojiList[rand.Intn(len(em.EmojiList))], nil
+// EmojiName returns the reaction name of emoji like ":sushi: "
+func EmojiName(emoji string) string {
+	return strings.Trim(emoji, ": ")
 }
 
 // UpdateEmojiList is the function to renew emoji list
@@ -36,13 +62,32 @@ func (em *EmojiManager) UpdateEmojiList() error {
 	if err != nil {
 		return err
 	}
+
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+
 	em.EmojiList = emojiList
 	em.LastUpdatedAt = time.Now()
 	return nil
 }
 
+// currentEmojiList returns the emoji list, renewing it first when it is older than emojiKeepInterval
+func (em *EmojiManager) currentEmojiList() []string {
+	em.mutex.RLock()
+	stale := em.LastUpdatedAt.IsZero() || time.Now().After(em.LastUpdatedAt.Add(emojiKeepInterval))
+	em.mutex.RUnlock()
+	if stale {
+		em.UpdateEmojiList()
+	}
+
+	// The list is replaced as a whole on update, so the returned one is never modified
+	em.mutex.RLock()
+	defer em.mutex.RUnlock()
+	return em.EmojiList
+}
+
 // GetEmojiList is the function to get emoji list from workspace
-func GetEmojiList(client *slack.Client) ([]string, error) {
+func GetEmojiList(client SlackAPI) ([]string, error) {
 	emojiMap, err := client.GetEmoji()
 	if err != nil {
 		return nil, err
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"slack-waiter-bot/fakeslack"
+	"sync"
+	"testing"
+)
+
+func TestEmojiManagerConcurrently(t *testing.T) {
+	emojiManager := &EmojiManager{Client: fakeslack.New("UBOT")}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if emoji, err := emojiManager.GetRandomEmoji(); err != nil || emoji != ":sushi: " {
+					t.Errorf("emoji = %q, %v, want the custom emoji", emoji, err)
+				}
+				if emoji, err := emojiManager.GetRandomEmojiExcept(map[string]bool{"sushi": true}); err != nil || emoji == ":sushi: " {
+					t.Errorf("emoji = %q, %v, want a standard emoji", emoji, err)
+				}
+				if err := emojiManager.UpdateEmojiList(); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
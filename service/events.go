@@ -1,14 +1,33 @@
 package service
 
 import (
-	"slack-waiter-bot/ids"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 )
 
+// ErrBoardAlreadyExists is returned when the thread already has a menu board
+var ErrBoardAlreadyExists = errors.New("menu board already exists in the thread")
+
+var mentionPattern = regexp.MustCompile(`<@([A-Z0-9]+)(?:\|[^>]*)?>`)
+
+// ReplyToggle is how a thread reply changes the choice of its menu
+type ReplyToggle int
+
+// Thread reply toggles, "+짜장면" selects, "-짜장면" unselects and "짜장면" toggles
+const (
+	ReplyToggleBoth ReplyToggle = iota
+	ReplyToggleSelect
+	ReplyToggleUnselect
+)
+
 // HandleAppMentionEvent handles when user mention bot
-func HandleAppMentionEvent(event *slackevents.AppMentionEvent, eh *Handler) {
+func HandleAppMentionEvent(event *slackevents.AppMentionEvent, eh *Handler) error {
 	var timeStamp string
 	if event.ThreadTimeStamp != "" {
 		timeStamp = event.ThreadTimeStamp
@@ -16,26 +35,220 @@ func HandleAppMentionEvent(event *slackevents.AppMentionEvent, eh *Handler) {
 		timeStamp = event.TimeStamp
 	}
 
-	messages, _, _, _ := eh.Client.GetConversationReplies(&slack.GetConversationRepliesParameters{ChannelID: event.Channel, Timestamp: timeStamp})
-	for _, msg := range messages {
-		if msg.User == eh.BotUserID {
-			return
+	ctx := &CommandContext{
+		Handler:         eh,
+		ChannelID:       event.Channel,
+		ThreadTimestamp: timeStamp,
+		UserID:          event.User,
+		Reply: func(text string, ephemeral bool) error {
+			if ephemeral {
+				_, err := eh.Client.PostEphemeral(event.Channel, event.User, slack.MsgOptionText(text, false), slack.MsgOptionTS(timeStamp))
+				return err
+			}
+			_, _, err := eh.Client.PostMessage(event.Channel, slack.MsgOptionText(text, false), slack.MsgOptionTS(timeStamp))
+			return err
+		},
+	}
+
+	// Subcommands are routed like /waiter with mentions of other users kept, other text is parsed as menus, deadline or template
+	text := strings.ReplaceAll(event.Text, "<@"+eh.BotUserID+">", "")
+	name, args := SplitCommand(text)
+	if command, ok := eh.Commands.Lookup(name); ok {
+		return command.Run(ctx, args)
+	}
+	request, err := ParseMention(mentionPattern.ReplaceAllString(text, ""))
+	if err != nil {
+		return ctx.Reply(fmt.Sprintf("%v\n\n%s", err, MentionUsage), true)
+	}
+	return RunMentionRequest(ctx, request)
+}
+
+// StartBoard posts the new menu board, into the thread when threadTimestamp is given
+func StartBoard(handler *Handler, channelID string, threadTimestamp string, menuBoard *MenuBoard) error {
+	if threadTimestamp != "" {
+		// Concurrent mentions in a thread must not create boards twice
+		claimKey := "mention/" + channelID + "/" + threadTimestamp
+		if !handler.Idempotency.Claim(claimKey) {
+			return ErrBoardAlreadyExists
+		}
+
+		messages, _, _, err := handler.Client.GetConversationReplies(&slack.GetConversationRepliesParameters{ChannelID: channelID, Timestamp: threadTimestamp})
+		if err != nil {
+			handler.Idempotency.Release(claimKey)
+			return err
+		}
+		for _, msg := range messages {
+			if msg.User == handler.BotUserID {
+				return ErrBoardAlreadyExists
+			}
+		}
+		err = PostNewBoard(handler, channelID, threadTimestamp, menuBoard)
+		// Let the thread be retried when the board was never posted
+		if err != nil && menuBoard.Timestamp == "" {
+			handler.Idempotency.Release(claimKey)
+		}
+		return err
+	}
+	return PostNewBoard(handler, channelID, threadTimestamp, menuBoard)
+}
+
+// PostNewBoard posts the menu board even if the thread already has boards, and keeps it as open
+func PostNewBoard(handler *Handler, channelID string, threadTimestamp string, menuBoard *MenuBoard) error {
+	if err := PostMenuBoard(handler.Client, menuBoard, channelID, threadTimestamp); err != nil {
+		return err
+	}
+	menuNames := []string{}
+	for _, menu := range menuBoard.Menus {
+		menuNames = append(menuNames, menu.MenuName)
+	}
+	if err := AddMenuReactions(handler, menuBoard, menuNames); err != nil {
+		return err
+	}
+	if err := AddActiveBoard(handler, menuBoard); err != nil {
+		return err
+	}
+	if !menuBoard.Deadline.IsZero() {
+		if err := ScheduleDeadline(handler, menuBoard); err != nil {
+			return err
+		}
+	}
+	return AuditBoard(handler, menuBoard, menuBoard.HostUserID, AuditOpen, "", menuBoard.Title)
+}
+
+// AddActiveBoard keeps the board as open with its permalink, which App Home links without asking slack again
+func AddActiveBoard(handler *Handler, menuBoard *MenuBoard) error {
+	activeBoard := NewActiveBoard(handler.TeamID, menuBoard)
+	permalink, err := handler.Client.GetPermalink(&slack.PermalinkParameters{Channel: menuBoard.ChannelID, Ts: menuBoard.Timestamp})
+	if err != nil {
+		handler.Logger.Printf("[ERROR] Failed to get permalink of board: %v\n", err)
+	}
+	activeBoard.Permalink = permalink
+	return handler.Store.AddActiveBoard(activeBoard)
+}
+
+// SaveOpenBoard saves the board message and keeps its co-hosts and choices for App Home
+func SaveOpenBoard(handler *Handler, menuBoard *MenuBoard) error {
+	if err := SaveMenuBoard(handler.Client, menuBoard); err != nil {
+		return err
+	}
+	return handler.Store.SetActiveBoardChoices(handler.TeamID, menuBoard.ChannelID, menuBoard.Timestamp, menuBoard.CohostUserIDs, newMenuRecords(menuBoard))
+}
+
+// FindThreadMenuBoard loads the latest open menu board the bot posted in the thread, or the latest one if all are terminated
+func FindThreadMenuBoard(handler *Handler, channelID string, threadTimestamp string) (*MenuBoard, error) {
+	messages, _, _, err := handler.Client.GetConversationReplies(&slack.GetConversationRepliesParameters{ChannelID: channelID, Timestamp: threadTimestamp})
+	if err != nil {
+		return nil, err
+	}
+
+	var terminatedBoard *MenuBoard
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].User != handler.BotUserID || len(messages[i].Blocks.BlockSet) == 0 {
+			continue
+		}
+		if messages[i].Blocks.BlockSet[0].BlockType() != slack.MBTHeader {
+			continue
+		}
+		menuBoard, err := LoadMenuBoard(handler.Client, channelID, messages[i].Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		if !menuBoard.IsTerminated() {
+			return menuBoard, nil
+		}
+		if terminatedBoard == nil {
+			terminatedBoard = menuBoard
 		}
 	}
+	if terminatedBoard != nil {
+		return terminatedBoard, nil
+	}
+	return nil, ErrMenuBoardNotFound
+}
 
-	headerText := slack.NewTextBlockObject("plain_text", "Menu", false, false)
-	headerBlock := slack.NewHeaderBlock(headerText)
+// ParseReplyToggle parses thread reply into the toggle and the menu query
+func ParseReplyToggle(text string) (ReplyToggle, string) {
+	text = strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(text, "+"):
+		return ReplyToggleSelect, strings.TrimSpace(text[1:])
+	case strings.HasPrefix(text, "-"):
+		return ReplyToggleUnselect, strings.TrimSpace(text[1:])
+	}
+	return ReplyToggleBoth, text
+}
 
-	addMenuBtnTxt := slack.NewTextBlockObject("plain_text", "➕", false, false)
-	addMenuBtn := slack.NewButtonBlockElement(ids.AddMenu, ids.AddMenu, addMenuBtnTxt)
-	deleteMenuBtnTxt := slack.NewTextBlockObject("plain_text", "➖", false, false)
-	deleteMenuBtn := slack.NewButtonBlockElement(ids.DeleteMenu, ids.DeleteMenu, deleteMenuBtnTxt)
-	OrderForOtherBtnTxt := slack.NewTextBlockObject("plain_text", "👥", false, false)
-	OrderForOtherBtn := slack.NewButtonBlockElement(ids.OrderForOther, ids.OrderForOther, OrderForOtherBtnTxt)
-	terminateBtnTxt := slack.NewTextBlockObject("plain_text", "🚫", false, false)
-	terminateBtn := slack.NewButtonBlockElement(ids.TerminateMenu, ids.TerminateMenu, terminateBtnTxt).WithStyle(slack.StyleDanger)
+// HandleThreadReplyEvent toggles the menu named by a reply in the thread of a menu board
+func HandleThreadReplyEvent(event *slackevents.MessageEvent, eh *Handler) error {
+	// Mentions are handled as app mention events, and edits or bot messages are not replies
+	if event.ThreadTimeStamp == "" || event.ThreadTimeStamp == event.TimeStamp || event.SubType != "" || event.BotID != "" || event.User == "" || event.User == eh.BotUserID || strings.Contains(event.Text, "<@"+eh.BotUserID+">") {
+		return nil
+	}
+	toggle, query := ParseReplyToggle(event.Text)
+	if query == "" || strings.Contains(query, "\n") || len([]rune(query)) > maxMenuNameLength {
+		return nil
+	}
+	// Most replies are chat in threads without boards, which should not cost api calls
+	if !eh.Store.HasActiveThread(eh.TeamID, event.Channel, event.ThreadTimeStamp) {
+		return nil
+	}
 
-	ButtonBlock := slack.NewActionBlock(ids.MenuButtonsBlock, addMenuBtn, deleteMenuBtn, OrderForOtherBtn, terminateBtn)
+	menuBoard, err := FindThreadMenuBoard(eh, event.Channel, event.ThreadTimeStamp)
+	if err == ErrMenuBoardNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Replies can not change reactions, so boards of reaction mode are chosen only by reactions
+	if menuBoard.IsTerminated() || menuBoard.ReactionMode {
+		return nil
+	}
+	profile, err := eh.Profiles.GetProfile(event.User)
+	if err != nil {
+		return err
+	}
+
+	messageUpdateMutex.Lock()
+	defer messageUpdateMutex.Unlock()
+
+	menuBoard, err = LoadMenuBoard(eh.Client, menuBoard.ChannelID, menuBoard.Timestamp)
+	if err != nil {
+		return err
+	}
+	if menuBoard.IsTerminated() {
+		return nil
+	}
+	if !menuBoard.Deadline.IsZero() && time.Now().After(menuBoard.Deadline) {
+		return CloseMenuBoard(eh, menuBoard, "")
+	}
 
-	eh.Client.PostMessage(event.Channel, slack.MsgOptionBlocks(headerBlock, slack.NewDividerBlock(), ButtonBlock), slack.MsgOptionTS(timeStamp))
+	// Replies naming no menu are chat, which the bot leaves alone unless they are explicit orders or ambiguous typos
+	menuNames := menuBoard.MatchMenuNames(query)
+	if len(menuNames) > 1 || (len(menuNames) == 0 && toggle != ReplyToggleBoth) {
+		return eh.Client.AddReaction("question", slack.NewRefToMessage(event.Channel, event.TimeStamp))
+	}
+	if len(menuNames) == 0 {
+		return nil
+	}
+	menuName := menuNames[0]
+	chosen := menuBoard.HasChosen(menuName, profile.RealName)
+	if toggle == ReplyToggleBoth || (toggle == ReplyToggleSelect && !chosen) || (toggle == ReplyToggleUnselect && chosen) {
+		menuBoard.ToggleMenuByUser(profile, menuName)
+		if err := SaveOpenBoard(eh, menuBoard); err != nil {
+			return err
+		}
+		if err := RecordToggle(eh, menuBoard, event.User, menuName, []*slack.UserProfile{profile}); err != nil {
+			return err
+		}
+		if err := AuditToggles(eh, menuBoard, event.User, menuName, []string{profile.RealName}, "reply"); err != nil {
+			return err
+		}
+		if menuBoard.HasChosen(menuName, profile.RealName) {
+			if err := WarnDietConflict(eh, menuBoard, event.User, menuName); err != nil {
+				return err
+			}
+		}
+	}
+	return eh.Client.AddReaction("white_check_mark", slack.NewRefToMessage(event.Channel, event.TimeStamp))
 }
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestHandleThreadReplyEvent(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+	fake.AddUser("U2", "손님")
+
+	menuBoard := NewMenuBoard("점심", "U1")
+	menuBoard.AddMenu("짜장면", "", "U1")
+	menuBoard.AddMenu("짬뽕", "", "U1")
+	menuBoard.AddMenu("김치 볶음밥", "", "U1")
+	menuBoard.AddMenu("김치 덮밥", "", "U1")
+	if err := PostNewBoard(handler, "C1", "", menuBoard); err != nil {
+		t.Fatal(err)
+	}
+	otherThread := fake.PostUserMessage("C1", "U1", "점심 뭐 먹지", "")
+
+	reply := func(threadTimestamp string, text string) string {
+		timestamp := fake.PostUserMessage("C1", "U2", text, threadTimestamp)
+		event := &slackevents.MessageEvent{Channel: "C1", User: "U2", Text: text, TimeStamp: timestamp, ThreadTimeStamp: threadTimestamp}
+		if err := HandleThreadReplyEvent(event, handler); err != nil {
+			t.Fatal(err)
+		}
+		return timestamp
+	}
+	reactions := func(timestamp string) []slack.ItemReaction {
+		reactions, err := fake.GetReactions(slack.NewRefToMessage("C1", timestamp), slack.GetReactionsParameters{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reactions
+	}
+	choosers := func(menuName string) []string {
+		loaded, err := LoadMenuBoard(handler.Client, "C1", menuBoard.Timestamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return loaded.Menus[loaded.MenuNameIndexMap[menuName]].GetChoosers()
+	}
+
+	reactionNames := func(timestamp string) []string {
+		names := []string{}
+		for _, reaction := range reactions(timestamp) {
+			names = append(names, reaction.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		text          string
+		wantReactions []string
+	}{
+		{text: "짜장면", wantReactions: []string{"white_check_mark"}},
+		{text: "+짬봉", wantReactions: []string{"question"}},
+		{text: "-탕수육", wantReactions: []string{"question"}},
+		{text: "김치볶밥", wantReactions: []string{"question"}},
+		// Chat naming no menu is left alone
+		{text: "짜장 좋다", wantReactions: []string{}},
+	}
+	for _, test := range tests {
+		if names := reactionNames(reply(menuBoard.ThreadTimestamp, test.text)); !reflect.DeepEqual(names, test.wantReactions) {
+			t.Errorf("reactions to %q = %q, want %q", test.text, names, test.wantReactions)
+		}
+	}
+	if got := choosers("짜장면"); len(got) != 1 || got[0] != "손님" {
+		t.Errorf("choosers = %q, want the replier", got)
+	}
+	for _, menuName := range []string{"김치 볶음밥", "김치 덮밥"} {
+		if got := choosers(menuName); len(got) != 0 {
+			t.Errorf("choosers of %s = %q, want none for the ambiguous reply", menuName, got)
+		}
+	}
+
+	// Threads without boards are not looked up
+	if timestamp := reply(otherThread, "짬뽕"); len(reactions(timestamp)) != 0 {
+		t.Errorf("reactions = %+v, want none", reactions(timestamp))
+	}
+	if got := choosers("짬뽕"); len(got) != 0 {
+		t.Errorf("choosers = %q, want none", got)
+	}
+}
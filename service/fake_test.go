@@ -0,0 +1,44 @@
+package service
+
+import (
+	"io/ioutil"
+	"log"
+	"slack-waiter-bot/fakeslack"
+	"testing"
+	"time"
+)
+
+// newTestHandler returns a handler of a single workspace served by fake slack
+func newTestHandler(t *testing.T) (*Handler, *fakeslack.Slack) {
+	fake := fakeslack.New("UBOT")
+	store, err := NewStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := log.New(ioutil.Discard, "", 0)
+	handler := &Handler{
+		Client:       fake,
+		BotUserID:    "UBOT",
+		EmojiManager: &EmojiManager{Client: fake},
+		Dispatcher:   NewDispatcher(logger, 1, 64),
+		Idempotency:  NewIdempotencyCache(time.Minute),
+		Profiles:     NewProfileCache(fake, time.Minute),
+		Store:        store,
+		Audit:        NewAuditLog(""),
+		Commands:     NewCommandRouter(),
+		TeamID:       "T1",
+		Logger:       logger,
+	}
+	return handler, fake
+}
+
+// waitFor waits until the condition holds, for jobs run by the dispatcher
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
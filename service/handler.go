@@ -2,10 +2,10 @@ package service
 
 import (
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"runtime/debug"
 	"slack-waiter-bot/ids"
 	"time"
 
@@ -13,23 +13,51 @@ import (
 	"github.com/slack-go/slack/slackevents"
 )
 
+// defaultSyncResponseTimeout leaves room in the 3 seconds slack waits for the response of view submissions and options
+const defaultSyncResponseTimeout = 2 * time.Second
+
 // Handler for handling slack events and actions
 type Handler struct {
-	Client        *slack.Client
+	Client        SlackAPI
 	SigningSecret string
 	BotUserID     string
 	EmojiManager  *EmojiManager
-	Logger        *log.Logger
+	Dispatcher    *Dispatcher
+	Idempotency   *IdempotencyCache
+	Profiles      *ProfileCache
+	Workspaces    *WorkspaceManager
+	Store         *Store
+	Audit         *AuditLog
+	Commands      *CommandRouter
+	TeamID        string
+	// SyncResponseTimeout bounds handling of view submissions and options slack waits for, defaultSyncResponseTimeout when zero
+	SyncResponseTimeout time.Duration
+	// Location is the timezone deadlines are written in and times are shown in, local time when nil
+	Location *time.Location
+	Logger   *log.Logger
+}
+
+// Now returns the current time in the location of the handler
+func (handler *Handler) Now() time.Time {
+	return handler.InLocation(time.Now())
+}
+
+// InLocation returns the time in the location of the handler
+func (handler *Handler) InLocation(t time.Time) time.Time {
+	if handler.Location == nil {
+		return t.Local()
+	}
+	return t.In(handler.Location)
 }
 
 // HandleStatus is the function to handle status api
 func (handler *Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "time": time.Now().Local().String()})
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "time": time.Now().Local().String(), "backlog": handler.Dispatcher.Backlog()})
 }
 
-// HandleEvent is the function to handle events
+// HandleEvent is the function to handle events, the request should be verified by VerifyRequest
 func (handler *Handler) HandleEvent(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -37,52 +65,120 @@ func (handler *Handler) HandleEvent(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	sv, err := slack.NewSecretsVerifier(r.Header, handler.SigningSecret)
+	eventsAPIEvent, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
 	if err != nil {
-		handler.Logger.Println("[INFO] Bad request")
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	if _, err := sv.Write(body); err != nil {
 		handler.Logger.Println("[INFO] Bad request")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	if err := sv.Ensure(); err != nil {
-		handler.Logger.Println("[INFO] Bad request")
-		w.WriteHeader(http.StatusUnauthorized)
+
+	if eventsAPIEvent.Type == slackevents.URLVerification {
+		handler.Logger.Println("[INFO] URL Verification")
+		URLVerification(w, body)
 		return
 	}
-	eventsAPIEvent, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+	if retryNum := r.Header.Get("X-Slack-Retry-Num"); retryNum != "" {
+		handler.Logger.Printf("[INFO] Event retry %s: %s\n", retryNum, r.Header.Get("X-Slack-Retry-Reason"))
+	}
+
+	// Slack redelivers events which are not acknowledged
+	if !handler.DispatchEvent(eventsAPIEvent) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// HandleAction is the function to handle actions, the request should be verified by VerifyRequest
+func (handler *Handler) HandleAction(w http.ResponseWriter, r *http.Request) {
+	var payload slack.InteractionCallback
+	err := json.Unmarshal([]byte(r.FormValue("payload")), &payload)
 	if err != nil {
 		handler.Logger.Println("[INFO] Bad request")
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	if eventsAPIEvent.Type == slackevents.URLVerification {
-		handler.Logger.Println("[INFO] URL Verification")
-		URLVerification(w, body)
+	if response := handler.DispatchAction(&payload); response != nil {
+		RespondJSON(w, response)
 	}
+}
 
+// DispatchEvent runs the handler of callback event regardless of the transport it came from,
+// and returns false when the event is dropped since the job queue is full
+func (handler *Handler) DispatchEvent(eventsAPIEvent slackevents.EventsAPIEvent) bool {
+	dispatched := true
 	if eventsAPIEvent.Type == slackevents.CallbackEvent {
+		claimKey := ""
+		if callbackEvent, ok := eventsAPIEvent.Data.(*slackevents.EventsAPICallbackEvent); ok {
+			claimKey = "event/" + callbackEvent.EventID
+			if !handler.Idempotency.Claim(claimKey) {
+				handler.Logger.Println("[INFO] Duplicated event")
+				return true
+			}
+		}
+		// Handle the event with the client and caches of the workspace it came from
+		teamHandler, err := handler.ForTeam(eventsAPIEvent.TeamID)
+		if err != nil {
+			handler.Logger.Printf("[INFO] Event of unknown team %s: %v\n", eventsAPIEvent.TeamID, err)
+			return true
+		}
+		handler = teamHandler
+
 		innerEvent := eventsAPIEvent.InnerEvent
 		switch event := innerEvent.Data.(type) {
 		case *slackevents.AppMentionEvent:
 			handler.Logger.Println("[INFO] App mentioned event")
-			go HandleAppMentionEvent(event, handler)
+			dispatched = handler.Dispatcher.Dispatch("app mention", func() error { return HandleAppMentionEvent(event, handler) })
+		case *slackevents.AppHomeOpenedEvent:
+			if event.Tab == "home" {
+				handler.Logger.Println("[INFO] App home opened event")
+				dispatched = handler.Dispatcher.Dispatch("app home", func() error { return PublishAppHome(handler, event.User) })
+			}
+		case *slackevents.MessageEvent:
+			if event.ThreadTimeStamp != "" && event.SubType == "" {
+				handler.Logger.Println("[INFO] Thread reply event")
+				dispatched = handler.Dispatcher.Dispatch("thread reply", func() error { return HandleThreadReplyEvent(event, handler) })
+			}
+		case *slackevents.ReactionAddedEvent:
+			if event.Item.Type == "message" && event.ItemUser == handler.BotUserID {
+				handler.Logger.Println("[INFO] Reaction added event")
+				dispatched = handler.Dispatcher.Dispatch("reaction added", func() error {
+					return HandleReactionEvent(handler, event.User, event.Reaction, event.Item.Channel, event.Item.Timestamp, true)
+				})
+			}
+		case *slackevents.ReactionRemovedEvent:
+			if event.Item.Type == "message" && event.ItemUser == handler.BotUserID {
+				handler.Logger.Println("[INFO] Reaction removed event")
+				dispatched = handler.Dispatcher.Dispatch("reaction removed", func() error {
+					return HandleReactionEvent(handler, event.User, event.Reaction, event.Item.Channel, event.Item.Timestamp, false)
+				})
+			}
+		case *slack.UserChangeEvent:
+			handler.Logger.Println("[INFO] User changed event")
+			handler.Profiles.Invalidate(event.User.ID)
+		}
+		// Let the redelivery of the event be handled
+		if !dispatched && claimKey != "" {
+			handler.Idempotency.Release(claimKey)
 		}
 	}
-
+	return dispatched
 }
 
-// HandleAction is the function to handle actions
-func (handler *Handler) HandleAction(w http.ResponseWriter, r *http.Request) {
-	var payload slack.InteractionCallback
-	err := json.Unmarshal([]byte(r.FormValue("payload")), &payload)
+// DispatchAction runs the handler of interaction regardless of the transport it came from
+// and returns the payload which should be responded synchronously, or nil
+func (handler *Handler) DispatchAction(payload *slack.InteractionCallback) interface{} {
+	// Only interactions with a trigger id can be told apart from their redeliveries
+	if payload.Type != slack.InteractionTypeBlockSuggestion && payload.TriggerID != "" && !handler.Idempotency.Claim("action/"+payload.TriggerID) {
+		handler.Logger.Println("[INFO] Duplicated action")
+		return nil
+	}
+	// Handle the interaction with the client and caches of the workspace it came from
+	teamHandler, err := handler.ForTeam(payload.Team.ID)
 	if err != nil {
-		fmt.Printf("Could not parse action response JSON: %v\n", err)
+		handler.Logger.Printf("[INFO] Interaction of unknown team %s: %v\n", payload.Team.ID, err)
+		return nil
 	}
+	handler = teamHandler
 
 	switch payload.Type {
 	case slack.InteractionTypeBlockActions:
@@ -90,34 +186,271 @@ func (handler *Handler) HandleAction(w http.ResponseWriter, r *http.Request) {
 			switch blockAction.ActionID {
 			case ids.AddMenu:
 				handler.Logger.Println("[INFO] Add menu action")
-				go AddMenu(handler, &payload)
+				handler.dispatchOrReportBusy("add menu", payload, func() error { return AddMenu(handler, payload) })
 			case ids.DeleteMenu:
 				handler.Logger.Println("[INFO] Delete menu action")
-				go DeleteMenu(handler, &payload)
+				handler.dispatchOrReportBusy("delete menu", payload, func() error { return DeleteMenu(handler, payload) })
 			case ids.OrderForOther:
 				handler.Logger.Println("[INFO] Order for other action")
-				go OrderForOther(handler, &payload)
+				handler.dispatchOrReportBusy("order for other", payload, func() error { return OrderForOther(handler, payload) })
 			case ids.TerminateMenu:
 				handler.Logger.Println("[INFO] Terminate menu action")
-				go TerminateMenu(handler, &payload)
+				handler.dispatchOrReportBusy("terminate menu", payload, func() error { return TerminateMenu(handler, payload) })
+			case ids.OpenStartBoard:
+				handler.Logger.Println("[INFO] Open start board action")
+				handler.dispatchOrReportBusy("open start board", payload, func() error { return OpenStartBoard(handler, payload) })
+			case ids.UndoBoardChange:
+				handler.Logger.Println("[INFO] Undo board change action")
+				handler.dispatchOrReportBusy("undo board change", payload, func() error { return UndoBoardChange(handler, payload) })
+			case ids.ExportAuditLog:
+				handler.Logger.Println("[INFO] Export audit log action")
+				handler.dispatchOrReportBusy("export audit log", payload, func() error { return ExportAuditLog(handler, payload) })
+			case ids.DietRestrictions:
+				handler.Logger.Println("[INFO] Diet restrictions action")
+				selectedOptions := blockAction.SelectedOptions
+				handler.dispatchOrReportBusy("diet restrictions", payload, func() error { return SaveDietFromHome(handler, payload, selectedOptions) })
+			case ids.RestoreMenu:
+				handler.Logger.Println("[INFO] Restore menu action")
+				value := blockAction.Value
+				handler.dispatchOrReportBusy("restore menu", payload, func() error { return RestoreMenu(handler, payload, value) })
 			case ids.SelectMenuByUser:
 				handler.Logger.Println("[INFO] Select menu action")
-				go SelectMenuByUser(handler, &payload, blockAction.Value)
+				selectedMenuName := blockAction.Value
+				handler.dispatchOrReportBusy("select menu", payload, func() error { return SelectMenuByUser(handler, payload, selectedMenuName) })
 			}
 		}
 	case slack.InteractionTypeViewSubmission:
 		switch payload.View.CallbackID {
 		case ids.SubmitMenuCallback:
+			response := handler.respondInTime("validate menu add", ids.SubmitMenuInputBlock, func(syncHandler *Handler) *slack.ViewSubmissionResponse {
+				if errors := ValidateMenuAdd(syncHandler, payload); len(errors) > 0 {
+					return slack.NewErrorsViewSubmissionResponse(errors)
+				}
+				return nil
+			})
+			if response != nil {
+				handler.Logger.Println("[INFO] Invalid menu add view")
+				return response
+			}
 			handler.Logger.Println("[INFO] Submit menu add view")
-			go SubmitMenuAdd(handler, &payload)
+			if response := handler.dispatchSubmission("submit menu add", payload, ids.SubmitMenuInputBlock, func() error { return SubmitMenuAdd(handler, payload) }); response != nil {
+				return response
+			}
 		case ids.SubmitOrderForOtherCallback:
+			handler.Logger.Println("[INFO] Preview order for others view")
+			return handler.respondInTime("preview order for other", ids.SubmitMenuInputBlock, func(syncHandler *Handler) *slack.ViewSubmissionResponse {
+				return PreviewOrderForOther(syncHandler, payload)
+			})
+		case ids.SubmitOrderForOtherConfirmCallback:
 			handler.Logger.Println("[INFO] Submit order for others view")
-			go SubmitOrderForOther(handler, &payload)
+			if response := handler.dispatchSubmission("submit order for other", payload, "", func() error { return SubmitOrderForOther(handler, payload) }); response != nil {
+				return response
+			}
+		case ids.SubmitStartBoardCallback:
+			if errors := ValidateStartBoard(handler, payload); len(errors) > 0 {
+				handler.Logger.Println("[INFO] Invalid start board view")
+				return slack.NewErrorsViewSubmissionResponse(errors)
+			}
+			handler.Logger.Println("[INFO] Submit start board view")
+			if response := handler.dispatchSubmission("submit start board", payload, ids.StartBoardChannelBlock, func() error { return SubmitStartBoard(handler, payload) }); response != nil {
+				return response
+			}
 		case ids.SubmitDeleteMenuCallback:
+			handler.Logger.Println("[INFO] Confirm delete menu view")
+			return handler.respondInTime("confirm menu delete", ids.SubmitMenuDeleteBlock, func(syncHandler *Handler) *slack.ViewSubmissionResponse {
+				return ConfirmMenuDelete(syncHandler, payload)
+			})
+		case ids.SubmitAuditLogCallback:
+			handler.Logger.Println("[INFO] Search audit log view")
+			return handler.respondInTime("search audit log", ids.AuditMenuBlock, func(syncHandler *Handler) *slack.ViewSubmissionResponse {
+				return SearchAuditLog(syncHandler, payload)
+			})
+		case ids.SubmitDeleteMenuConfirmCallback:
 			handler.Logger.Println("[INFO] Submit delete menu view")
-			go SubmitMenuDelete(handler, &payload)
+			if response := handler.dispatchSubmission("submit menu delete", payload, "", func() error { return SubmitMenuDelete(handler, payload) }); response != nil {
+				return response
+			}
+		}
+	case slack.InteractionTypeShortcut:
+		if payload.CallbackID == ids.StartBoardShortcut {
+			handler.Logger.Println("[INFO] Start board shortcut")
+			handler.dispatchOrReportBusy("start board shortcut", payload, func() error { return OpenStartBoard(handler, payload) })
+		}
+	case slack.InteractionTypeMessageAction:
+		switch payload.CallbackID {
+		case ids.StartBoardHereShortcut:
+			handler.Logger.Println("[INFO] Start board here shortcut")
+			handler.dispatchOrReportBusy("start board here", payload, func() error { return StartBoardHere(handler, payload) })
+		case ids.AuditLogShortcut:
+			handler.Logger.Println("[INFO] Audit log shortcut")
+			handler.dispatchOrReportBusy("audit log", payload, func() error { return OpenAuditLog(handler, payload) })
+		}
+	case slack.InteractionTypeBlockSuggestion:
+		handler.Logger.Println("[INFO] Menu options suggestion")
+		return handler.suggestInTime(payload)
+	}
+	return nil
+}
+
+// respondInTime runs the handler of view submission with slack calls which are not retried,
+// answering the error on the block instead when it does not finish while slack waits for the response
+func (handler *Handler) respondInTime(name string, blockID string, respond func(syncHandler *Handler) *slack.ViewSubmissionResponse) *slack.ViewSubmissionResponse {
+	var response *slack.ViewSubmissionResponse
+	if !handler.runInTime("view submission "+name, func(syncHandler *Handler) { response = respond(syncHandler) }) {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{blockID: failureText})
+	}
+	return response
+}
+
+// suggestInTime loads the options of external select with slack calls which are not retried,
+// answering no options instead when it does not finish while slack waits for the response
+func (handler *Handler) suggestInTime(payload *slack.InteractionCallback) slack.OptionsResponse {
+	var options []*slack.OptionBlockObject
+	if !handler.runInTime("menu options", func(syncHandler *Handler) { options = SuggestMenuOptions(syncHandler, payload) }) {
+		return slack.OptionsResponse{Options: []*slack.OptionBlockObject{}}
+	}
+	return slack.OptionsResponse{Options: options}
+}
+
+// runInTime runs the function with slack calls which are not retried, since rate limits would be waited out
+// long after slack gives up, and returns false when it panics or does not finish in time
+func (handler *Handler) runInTime(name string, run func(syncHandler *Handler)) bool {
+	syncHandler := *handler
+	if client, ok := handler.Client.(*RetryClient); ok {
+		syncHandler.Client = client.API
+		syncHandler.Profiles = handler.Profiles.WithClient(client.API)
+	}
+
+	done := make(chan bool, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				handler.Logger.Printf("[ERROR] Sync response of %s panicked: %v\n%s", name, r, debug.Stack())
+				done <- false
+			}
+		}()
+		run(&syncHandler)
+		done <- true
+	}()
+
+	select {
+	case ok := <-done:
+		return ok
+	case <-time.After(handler.syncResponseTimeout()):
+		handler.Logger.Printf("[ERROR] Sync response of %s timed out\n", name)
+		return false
+	}
+}
+
+func (handler *Handler) syncResponseTimeout() time.Duration {
+	if handler.SyncResponseTimeout > 0 {
+		return handler.SyncResponseTimeout
+	}
+	return defaultSyncResponseTimeout
+}
+
+// dispatchInteraction queues the interaction job and reports its failure to the user who triggered it,
+// and returns false when the queue is full, releasing the claim of the interaction to be tried again
+func (handler *Handler) dispatchInteraction(name string, payload *slack.InteractionCallback, run func() error) bool {
+	dispatched := handler.Dispatcher.Dispatch(name, func() error {
+		err := run()
+		if err != nil {
+			if reportErr := ReportFailure(handler, payload, err); reportErr != nil {
+				handler.Logger.Printf("[ERROR] Failed to report failure of %s: %v\n", name, reportErr)
+			}
+		}
+		return err
+	})
+	if !dispatched && payload.TriggerID != "" {
+		handler.Idempotency.Release("action/" + payload.TriggerID)
+	}
+	return dispatched
+}
+
+// dispatchOrReportBusy queues the interaction job, letting the user know when it is dropped
+func (handler *Handler) dispatchOrReportBusy(name string, payload *slack.InteractionCallback, run func() error) {
+	if handler.dispatchInteraction(name, payload, run) {
+		return
+	}
+	// The queue is full, so the reply does not wait in it
+	go func() {
+		if err := ReportBusy(handler, payload); err != nil {
+			handler.Logger.Printf("[ERROR] Failed to report busy %s: %v\n", name, err)
 		}
+	}()
+}
 
+// dispatchSubmission queues the job of view submission and returns nil to close the view,
+// or keeps the view open with the busy text on the input block, or under the view without inputs, when it is dropped
+func (handler *Handler) dispatchSubmission(name string, payload *slack.InteractionCallback, blockID string, run func() error) *slack.ViewSubmissionResponse {
+	if handler.dispatchInteraction(name, payload, run) {
+		return nil
+	}
+	if blockID != "" {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{blockID: busyText})
+	}
+	return slack.NewUpdateViewSubmissionResponse(NewBusyView(payload.View))
+}
+
+// HandleCommand is the function to handle slash commands, the request should be verified by VerifyRequest
+func (handler *Handler) HandleCommand(w http.ResponseWriter, r *http.Request) {
+	command, err := slack.SlashCommandParse(r)
+	if err != nil {
+		handler.Logger.Println("[INFO] Bad request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	handler.DispatchCommand(command)
+	w.WriteHeader(http.StatusOK)
+}
+
+// DispatchCommand runs the slash command regardless of the transport it came from, replying through its response url
+func (handler *Handler) DispatchCommand(command slack.SlashCommand) {
+	teamHandler, err := handler.ForTeam(command.TeamID)
+	if err != nil {
+		handler.Logger.Printf("[INFO] Command of unknown team %s: %v\n", command.TeamID, err)
+		return
+	}
+	handler = teamHandler
+
+	handler.Logger.Printf("[INFO] Slash command %s %s\n", command.Command, command.Text)
+	ctx := &CommandContext{
+		Handler:   handler,
+		ChannelID: command.ChannelID,
+		UserID:    command.UserID,
+		TriggerID: command.TriggerID,
+		Reply: func(text string, ephemeral bool) error {
+			return RespondToURL(command.ResponseURL, text, ephemeral)
+		},
+	}
+	dispatched := handler.Dispatcher.Dispatch("slash command", func() error {
+		err := handler.Commands.Route(ctx, command.Text)
+		if err != nil {
+			if replyErr := ctx.Reply(failureText, true); replyErr != nil {
+				handler.Logger.Printf("[ERROR] Failed to report failure of slash command: %v\n", replyErr)
+			}
+		}
+		return err
+	})
+	if !dispatched {
+		go func() {
+			if err := ctx.Reply(busyText, true); err != nil {
+				handler.Logger.Printf("[ERROR] Failed to report busy slash command: %v\n", err)
+			}
+		}()
+	}
+}
+
+// HandleOptions is the function to handle options load of external select menus, the request should be verified by VerifyRequest
+func (handler *Handler) HandleOptions(w http.ResponseWriter, r *http.Request) {
+	var payload slack.InteractionCallback
+	err := json.Unmarshal([]byte(r.FormValue("payload")), &payload)
+	if err != nil || payload.Type != slack.InteractionTypeBlockSuggestion {
+		handler.Logger.Println("[INFO] Bad request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
 	}
 
+	RespondJSON(w, handler.DispatchAction(&payload))
 }
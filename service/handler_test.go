@@ -0,0 +1,287 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slack-waiter-bot/fakeslack"
+	"slack-waiter-bot/ids"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestRespondInTime(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	handler.Client = NewRetryClient(fake, handler.Logger)
+	handler.SyncResponseTimeout = 50 * time.Millisecond
+
+	response := handler.respondInTime("fast", "block", func(syncHandler *Handler) *slack.ViewSubmissionResponse {
+		if syncHandler.Client != fake || syncHandler.Profiles.Client != fake {
+			t.Error("view submission is handled with the retrying client")
+		}
+		return slack.NewClearViewSubmissionResponse()
+	})
+	if response == nil || response.ResponseAction != slack.RAClear {
+		t.Errorf("response = %+v, want the response of the handler", response)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	start := time.Now()
+	response = handler.respondInTime("slow", "block", func(syncHandler *Handler) *slack.ViewSubmissionResponse {
+		<-release
+		return nil
+	})
+	if response == nil || response.ResponseAction != slack.RAErrors || response.Errors["block"] == "" {
+		t.Errorf("response = %+v, want error on the block", response)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %s, want to give up at the timeout", elapsed)
+	}
+
+	response = handler.respondInTime("panic", "block", func(syncHandler *Handler) *slack.ViewSubmissionResponse {
+		panic("broken view")
+	})
+	if response == nil || response.Errors["block"] == "" {
+		t.Errorf("response = %+v, want error on the block", response)
+	}
+}
+
+// slowSlack holds conversation replies until released, like slack waiting out a rate limit
+type slowSlack struct {
+	*fakeslack.Slack
+	release chan struct{}
+}
+
+func (s *slowSlack) GetConversationReplies(params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error) {
+	<-s.release
+	return s.Slack.GetConversationReplies(params)
+}
+
+func TestSuggestInTime(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+	handler.Client = NewRetryClient(fake, handler.Logger)
+	handler.SyncResponseTimeout = 50 * time.Millisecond
+
+	menuBoard := NewMenuBoard("점심", "U1")
+	menuBoard.AddMenu("짜장면", "", "U1")
+	menuBoard.AddMenu("짬뽕", "", "U1")
+	if err := PostMenuBoard(fake, menuBoard, "C1", ""); err != nil {
+		t.Fatal(err)
+	}
+	payload := &slack.InteractionCallback{Type: slack.InteractionTypeBlockSuggestion, Value: "짜장"}
+	payload.View.PrivateMetadata = WriteCallbackMetadata("C1", menuBoard.Timestamp)
+
+	response := handler.suggestInTime(payload)
+	if len(response.Options) != 1 || response.Options[0].Value != "짜장면" {
+		t.Errorf("options = %+v, want the menu searched", response.Options)
+	}
+
+	slow := &slowSlack{Slack: fake, release: make(chan struct{})}
+	defer close(slow.release)
+	handler.Client = NewRetryClient(slow, handler.Logger)
+	start := time.Now()
+	response = handler.suggestInTime(payload)
+	if response.Options == nil || len(response.Options) != 0 {
+		t.Errorf("options = %+v, want empty options", response.Options)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %s, want to give up at the timeout", elapsed)
+	}
+}
+
+// sendEvent posts the event callback to the events endpoint like slack does
+func sendEvent(t *testing.T, handler *Handler, event string) {
+	t.Helper()
+	body := `{"type":"event_callback","team_id":"T1","event_id":"Ev` + strconv.FormatInt(time.Now().UnixNano(), 10) + `","event":` + event + `}`
+	recorder := httptest.NewRecorder()
+	handler.HandleEvent(recorder, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("event status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+}
+
+// sendAction posts the interaction payload to the actions endpoint like slack does and returns the response body
+func sendAction(t *testing.T, handler *Handler, payload string) string {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodPost, "/actions", strings.NewReader(url.Values{"payload": {payload}}.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	handler.HandleAction(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("action status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	return recorder.Body.String()
+}
+
+// blockAction returns the payload of the user clicking the button on the message, block actions are told apart by their block id
+func blockAction(userID string, timestamp string, actionID string, value string) string {
+	return fmt.Sprintf(`{"type":"block_actions","trigger_id":"%s/%s/%d","team":{"id":"T1"},"user":{"id":"%s"},"channel":{"id":"C1"},"message":{"ts":"%s"},"actions":[{"type":"button","block_id":"%s","action_id":"%s","value":%q}]}`,
+		userID, actionID, time.Now().UnixNano(), userID, timestamp, actionID, actionID, value)
+}
+
+// menuAddSubmission returns the payload of the user submitting the menu add view
+func menuAddSubmission(userID string, view slack.ModalViewRequest, menuName string, people []string) string {
+	selectedUsers, _ := json.Marshal(people)
+	return fmt.Sprintf(`{"type":"view_submission","trigger_id":"%s/submit/%d","team":{"id":"T1"},"user":{"id":"%s"},"view":{"callback_id":"%s","private_metadata":%q,"state":{"values":{
+		"%s":{"%s":{"type":"plain_text_input","value":%q}},
+		"%s":{"%s":{"type":"multi_users_select","selected_users":%s}}}}}}`,
+		userID, time.Now().UnixNano(), userID, view.CallbackID, view.PrivateMetadata,
+		ids.SubmitMenuInputBlock, ids.SubmitMenuInput, menuName,
+		ids.SubmitMenuSelectPeopleBlock, ids.SubmitMenuPeople, selectedUsers)
+}
+
+func TestHandlerBoardFlow(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+	fake.AddUser("U2", "김철수")
+	fake.AddUser("U3", "박영희")
+
+	// Mention opens a board in the thread of the mention
+	mentionTimestamp := fake.PostUserMessage("C1", "U1", "<@UBOT> 짜장면, 짬뽕", "")
+	sendEvent(t, handler, fmt.Sprintf(`{"type":"app_mention","user":"U1","text":"<@UBOT> 짜장면, 짬뽕","ts":"%s","channel":"C1"}`, mentionTimestamp))
+	boardTimestamp := ""
+	waitFor(t, "the board posted", func() bool {
+		for _, message := range fake.Messages("C1") {
+			if message.User == "UBOT" && len(message.Blocks.BlockSet) > 0 && message.Blocks.BlockSet[0].BlockType() == slack.MBTHeader {
+				boardTimestamp = message.Timestamp
+				return true
+			}
+		}
+		return false
+	})
+	choosers := func(menuName string) []string {
+		menuBoard, err := LoadMenuBoard(fake, "C1", boardTimestamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		menuIndex, ok := menuBoard.MenuNameIndexMap[menuName]
+		if !ok {
+			return nil
+		}
+		return menuBoard.Menus[menuIndex].GetChoosers()
+	}
+
+	// Clicking the button of the menu selects it
+	sendAction(t, handler, blockAction("U2", boardTimestamp, ids.SelectMenuByUser, "짜장면"))
+	waitFor(t, "the menu selected", func() bool { return reflect.DeepEqual(choosers("짜장면"), []string{"김철수"}) })
+
+	// Adding a menu opens the view, which adds the menu with the people chosen
+	sendAction(t, handler, blockAction("U1", boardTimestamp, ids.AddMenu, ids.AddMenu))
+	waitFor(t, "the menu add view opened", func() bool { return len(fake.Views()) == 1 })
+	view := fake.Views()[0]
+	if response := sendAction(t, handler, menuAddSubmission("U1", view, "짬뽕", []string{"U3"})); !strings.Contains(response, ids.SubmitMenuInputBlock) {
+		t.Errorf("response of duplicate menu = %q, want error on the menu input", response)
+	}
+	if response := sendAction(t, handler, menuAddSubmission("U1", view, " 탕수육 ", []string{"U3"})); response != "" {
+		t.Errorf("response of new menu = %q, want empty to close the view", response)
+	}
+	waitFor(t, "the menu added", func() bool { return reflect.DeepEqual(choosers("탕수육"), []string{"박영희"}) })
+
+	// Only the host closes the board
+	sendAction(t, handler, blockAction("U2", boardTimestamp, ids.TerminateMenu, ids.TerminateMenu))
+	waitFor(t, "the refusal", func() bool {
+		for _, ephemeral := range fake.Ephemerals() {
+			if ephemeral.UserID == "U2" && strings.Contains(ephemeral.Text, "마감할 수 있다옹") {
+				return true
+			}
+		}
+		return false
+	})
+	sendAction(t, handler, blockAction("U1", boardTimestamp, ids.TerminateMenu, ids.TerminateMenu))
+	waitFor(t, "the board closed", func() bool {
+		menuBoard, err := LoadMenuBoard(fake, "C1", boardTimestamp)
+		return err == nil && menuBoard.IsTerminated()
+	})
+
+	records := handler.Store.ChannelBoards("T1", "C1")
+	if len(records) != 1 {
+		t.Fatalf("records = %+v, want the closed board", records)
+	}
+	wantMenus := []MenuRecord{
+		{MenuName: "짜장면", Choosers: []string{"김철수"}},
+		{MenuName: "짬뽕", Choosers: []string{}},
+		{MenuName: "탕수육", Choosers: []string{"박영희"}},
+	}
+	if !reflect.DeepEqual(records[0].Menus, wantMenus) {
+		t.Errorf("menus = %+v, want %+v", records[0].Menus, wantMenus)
+	}
+	if records[0].HostUserID != "U1" {
+		t.Errorf("host = %s, want U1", records[0].HostUserID)
+	}
+	if boards := handler.Store.ActiveBoards("T1"); len(boards) != 0 {
+		t.Errorf("open boards = %+v, want none", boards)
+	}
+}
+
+func TestHandlerBusy(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+	menuBoard := NewMenuBoard("점심", "U1")
+	menuBoard.AddMenu("짜장면", "", "U1")
+	if err := PostNewBoard(handler, "C1", "", menuBoard); err != nil {
+		t.Fatal(err)
+	}
+	workers := handler.Dispatcher
+	// Without workers nor room in the queue, every job is dropped
+	handler.Dispatcher = NewDispatcher(handler.Logger, 0, 0)
+
+	// Dropped events are not acknowledged to be redelivered
+	mentionTimestamp := fake.PostUserMessage("C1", "U1", "<@UBOT> 짬뽕", menuBoard.Timestamp)
+	event := fmt.Sprintf(`{"type":"event_callback","team_id":"T1","event_id":"EvBusy","event":{"type":"app_mention","user":"U1","text":"<@UBOT> 짬뽕","ts":"%s","thread_ts":"%s","channel":"C1"}}`, mentionTimestamp, menuBoard.Timestamp)
+	sendBusyEvent := func() int {
+		recorder := httptest.NewRecorder()
+		handler.HandleEvent(recorder, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(event)))
+		return recorder.Code
+	}
+	if code := sendBusyEvent(); code != http.StatusServiceUnavailable {
+		t.Errorf("event status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	// Dropped actions are told to the user
+	click := blockAction("U1", menuBoard.Timestamp, ids.SelectMenuByUser, "짜장면")
+	sendAction(t, handler, click)
+	waitFor(t, "the busy reply", func() bool {
+		for _, ephemeral := range fake.Ephemerals() {
+			if ephemeral.UserID == "U1" && ephemeral.Text == busyText {
+				return true
+			}
+		}
+		return false
+	})
+
+	// Dropped submissions keep the view open
+	view := slack.ModalViewRequest{CallbackID: ids.SubmitMenuCallback, PrivateMetadata: WriteCallbackMetadata("C1", menuBoard.Timestamp)}
+	if response := sendAction(t, handler, menuAddSubmission("U1", view, "탕수육", []string{})); !strings.Contains(response, ids.SubmitMenuInputBlock) || !strings.Contains(response, busyText) {
+		t.Errorf("response of menu add = %q, want the busy error on the menu input", response)
+	}
+	confirm := fmt.Sprintf(`{"type":"view_submission","trigger_id":"U1/confirm","team":{"id":"T1"},"user":{"id":"U1"},"view":{"callback_id":"%s","private_metadata":%q,"title":{"type":"plain_text","text":"메뉴 삭제"},"blocks":[{"type":"section","text":{"type":"mrkdwn","text":"*짜장면*를 지울거냐옹?"}}]}}`,
+		ids.SubmitDeleteMenuConfirmCallback, WriteMenuMetadata("C1", menuBoard.Timestamp, "짜장면"))
+	response := sendAction(t, handler, confirm)
+	if !strings.Contains(response, `"response_action":"update"`) || !strings.Contains(response, ids.BusyBlock) || !strings.Contains(response, ids.SubmitDeleteMenuConfirmCallback) {
+		t.Errorf("response of menu delete = %q, want the view updated with the busy text", response)
+	}
+
+	// Once jobs run again, the redelivered event and the same click are handled
+	handler.Dispatcher = workers
+	if code := sendBusyEvent(); code != http.StatusOK {
+		t.Errorf("event status = %d, want %d", code, http.StatusOK)
+	}
+	sendAction(t, handler, click)
+	waitFor(t, "the menu added by the redelivered event", func() bool {
+		loaded, err := LoadMenuBoard(fake, "C1", menuBoard.Timestamp)
+		return err == nil && len(loaded.Menus) == 2
+	})
+	waitFor(t, "the menu selected by the click", func() bool {
+		loaded, err := LoadMenuBoard(fake, "C1", menuBoard.Timestamp)
+		return err == nil && loaded.HasChosen("짜장면", "호스트")
+	})
+}
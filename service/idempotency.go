@@ -0,0 +1,52 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// IdempotencyCache remembers claimed keys for TTL so that redelivered events and actions are processed at most once
+type IdempotencyCache struct {
+	TTL         time.Duration
+	mutex       sync.Mutex
+	claimedAt   map[string]time.Time
+	lastEvicted time.Time
+}
+
+// NewIdempotencyCache creates empty idempotency cache
+func NewIdempotencyCache(ttl time.Duration) *IdempotencyCache {
+	return &IdempotencyCache{
+		TTL:       ttl,
+		claimedAt: map[string]time.Time{},
+	}
+}
+
+// Claim returns true only for the first claim of the key within TTL
+func (c *IdempotencyCache) Claim(key string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastEvicted) > c.TTL {
+		for claimedKey, claimedAt := range c.claimedAt {
+			if now.Sub(claimedAt) > c.TTL {
+				delete(c.claimedAt, claimedKey)
+			}
+		}
+		c.lastEvicted = now
+	}
+
+	if claimedAt, ok := c.claimedAt[key]; ok && now.Sub(claimedAt) <= c.TTL {
+		return false
+	}
+	c.claimedAt[key] = now
+	return true
+}
+
+// Release forgets the claim of the key so that it can be claimed again, for work which failed before taking effect
+func (c *IdempotencyCache) Release(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.claimedAt, key)
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+	cache := NewIdempotencyCache(time.Minute)
+	if !cache.Claim("action/1") {
+		t.Fatal("first claim was refused")
+	}
+	if cache.Claim("action/1") {
+		t.Fatal("second claim was accepted")
+	}
+	if !cache.Claim("action/2") {
+		t.Fatal("claim of another key was refused")
+	}
+	cache.Release("action/1")
+	if !cache.Claim("action/1") {
+		t.Fatal("claim after release was refused")
+	}
+
+	expiring := NewIdempotencyCache(time.Millisecond)
+	expiring.Claim("action/1")
+	time.Sleep(5 * time.Millisecond)
+	if !expiring.Claim("action/1") {
+		t.Fatal("claim after ttl was refused")
+	}
+}
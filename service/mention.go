@@ -0,0 +1,240 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// MentionUsage describes what the text of a mention can be
+const MentionUsage = "*멘션으로 부르기*\n" +
+	"• `@Waiter Bot` 메뉴판을 연다옹\n" +
+	"• `@Waiter Bot 짜장면, 짬뽕, 탕수육` 메뉴를 넣어서 연다옹\n" +
+	"• `@Waiter Bot 마감 12:30` 마감 시간을 정한다옹\n" +
+	"• `@Waiter Bot 중국집` 저장된 템플릿으로 연다옹\n" +
+	"• `@Waiter Bot new 디저트` 스레드에 메뉴판을 하나 더 연다옹\n"
+
+const deadlineKeyword = "마감"
+
+var deadlinePattern = regexp.MustCompile(`(?:^|[\s,])` + deadlineKeyword + `(?:\s+([^\s,]+))?(?:[\s,]|$)`)
+var clockPattern = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+
+// Clock is hour and minute of a day
+type Clock struct {
+	Hour   int
+	Minute int
+}
+
+// MentionRequest is what user asks by the text of a mention
+type MentionRequest struct {
+	// Menus to add, a single one may be the name of a template
+	Menus    []string
+	Deadline *Clock
+}
+
+// ParseMention parses "<menu>, <menu> 마감 <HH:MM>" where every part is optional
+func ParseMention(text string) (*MentionRequest, error) {
+	request := &MentionRequest{}
+	body := strings.TrimSpace(text)
+
+	if loc := deadlinePattern.FindStringSubmatchIndex(body); loc != nil {
+		if loc[2] < 0 {
+			return nil, errors.New("마감 시간이 없다옹. `마감 12:30` 처럼 적어달라옹")
+		}
+		clock, err := ParseClock(body[loc[2]:loc[3]])
+		if err != nil {
+			return nil, err
+		}
+		request.Deadline = clock
+		body = body[:loc[0]] + "," + body[loc[1]:]
+		if deadlinePattern.MatchString(body) {
+			return nil, errors.New("마감 시간은 하나만 적어달라옹")
+		}
+	}
+
+	request.Menus = SplitMenuNames(body)
+	return request, nil
+}
+
+// ParseClock parses "HH:MM" into clock
+func ParseClock(text string) (*Clock, error) {
+	matches := clockPattern.FindStringSubmatch(text)
+	if matches == nil {
+		return nil, fmt.Errorf("`%s`는 모르는 시간이다옹. `마감 12:30` 처럼 적어달라옹", text)
+	}
+	hour, _ := strconv.Atoi(matches[1])
+	minute, _ := strconv.Atoi(matches[2])
+	if hour > 23 || minute > 59 {
+		return nil, fmt.Errorf("`%s`는 없는 시간이다옹", text)
+	}
+	return &Clock{Hour: hour, Minute: minute}, nil
+}
+
+// On returns the time of the clock on the day of t
+func (c *Clock) On(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), c.Hour, c.Minute, 0, 0, t.Location())
+}
+
+// RunMentionRequest starts a board with the requested menus and deadline, or applies them to the board of the thread
+func RunMentionRequest(ctx *CommandContext, request *MentionRequest) error {
+	handler := ctx.Handler
+	title := ChannelBoardTitle(handler, ctx.ChannelID)
+	menus := request.Menus
+	if len(menus) == 1 {
+		if templateMenus, ok := handler.Store.Template(handler.TeamID, menus[0]); ok {
+			title, menus = menus[0], templateMenus
+		}
+	}
+
+	var deadline time.Time
+	if request.Deadline != nil {
+		now := handler.Now()
+		deadline = request.Deadline.On(now)
+		if !deadline.After(now) {
+			return ctx.Reply(fmt.Sprintf("%s는 이미 지난 시간이다옹", deadline.Format("15:04")), true)
+		}
+	}
+
+	menuBoard := NewChannelMenuBoard(handler, ctx.ChannelID, title, ctx.UserID)
+	for _, menuName := range menus {
+		if errorMessage := menuBoard.ValidateMenuName(menuName, ctx.UserID); errorMessage != "" {
+			return ctx.Reply(fmt.Sprintf("%s: %s", menuName, errorMessage), true)
+		}
+		menuBoard.AddMenu(menuName, PickMenuEmoji(handler, menuBoard), ctx.UserID)
+	}
+	if !deadline.IsZero() {
+		menuBoard.SetDeadline(deadline)
+	}
+
+	err := StartBoard(handler, ctx.ChannelID, ctx.ThreadTimestamp, menuBoard)
+	if err != ErrBoardAlreadyExists {
+		return err
+	}
+	if len(menus) == 0 && deadline.IsZero() {
+		return ctx.Reply("이 스레드에는 이미 메뉴판이 있다옹", true)
+	}
+	return updateThreadMenuBoard(ctx, menus, deadline)
+}
+
+// updateThreadMenuBoard adds menus and sets deadline to the existing board of the thread
+func updateThreadMenuBoard(ctx *CommandContext, menus []string, deadline time.Time) error {
+	handler := ctx.Handler
+	messageUpdateMutex.Lock()
+	defer messageUpdateMutex.Unlock()
+
+	menuBoard, err := FindThreadMenuBoard(handler, ctx.ChannelID, ctx.ThreadTimestamp)
+	if err == ErrMenuBoardNotFound {
+		return ctx.Reply("메뉴판을 만드는 중이다옹. 잠시 후 다시 해달라옹", true)
+	}
+	if err != nil {
+		return err
+	}
+	if menuBoard.IsTerminated() {
+		return ctx.Reply("이미 마감된 메뉴판이다옹", true)
+	}
+	if !deadline.IsZero() {
+		canManage, err := CanManageBoard(handler, menuBoard, ctx.UserID)
+		if err != nil {
+			return err
+		}
+		if !canManage {
+			return ctx.Reply("마감 시간은 메뉴판을 연 사람이나 같이 여는 사람만 정할 수 있다옹", true)
+		}
+	}
+
+	added, skipped := []string{}, []string{}
+	for _, menuName := range menus {
+		if _, ok := menuBoard.MenuNameIndexMap[menuName]; ok {
+			skipped = append(skipped, menuName)
+			continue
+		}
+		menuBoard.AddMenu(menuName, PickMenuEmoji(handler, menuBoard), ctx.UserID)
+		added = append(added, menuName)
+	}
+	if !deadline.IsZero() {
+		menuBoard.SetDeadline(deadline)
+	}
+	if err := SaveOpenBoard(handler, menuBoard); err != nil {
+		return err
+	}
+	if len(added) > 0 {
+		if err := RecordAdd(handler, menuBoard, ctx.UserID, added); err != nil {
+			return err
+		}
+	}
+	for _, menuName := range added {
+		if err := AuditBoard(handler, menuBoard, ctx.UserID, AuditAdd, menuName, "mention"); err != nil {
+			return err
+		}
+	}
+	if !deadline.IsZero() {
+		if err := AuditBoard(handler, menuBoard, ctx.UserID, AuditDeadline, "", deadline.Format(time.RFC3339)); err != nil {
+			return err
+		}
+	}
+	if err := AddMenuReactions(handler, menuBoard, added); err != nil {
+		return err
+	}
+
+	if !deadline.IsZero() {
+		if err := ScheduleDeadline(handler, menuBoard); err != nil {
+			return err
+		}
+	}
+	if len(skipped) > 0 {
+		return ctx.Reply(fmt.Sprintf("이미 있는 메뉴는 빼고 넣었다옹: %s", strings.Join(skipped, ", ")), true)
+	}
+	return nil
+}
+
+// ScheduleDeadline closes the board at its deadline unless the deadline is changed until then
+func ScheduleDeadline(handler *Handler, menuBoard *MenuBoard) error {
+	if err := handler.Store.SetActiveBoardDeadline(handler.TeamID, menuBoard.ChannelID, menuBoard.Timestamp, menuBoard.Deadline); err != nil {
+		return err
+	}
+	scheduleDeadline(handler, menuBoard.ChannelID, menuBoard.Timestamp, menuBoard.Deadline)
+	return nil
+}
+
+func scheduleDeadline(handler *Handler, channelID string, timestamp string, deadline time.Time) {
+	time.AfterFunc(time.Until(deadline), func() {
+		handler.Dispatcher.Dispatch("deadline", func() error { return CloseAtDeadline(handler, channelID, timestamp, deadline) })
+	})
+}
+
+// RestoreDeadlines schedules the deadlines of open boards kept in the store, whose timers are lost on restart
+func (handler *Handler) RestoreDeadlines() {
+	for _, board := range handler.Store.ActiveBoardsWithDeadline() {
+		teamHandler, err := handler.ForTeam(board.TeamID)
+		if err != nil {
+			handler.Logger.Printf("[ERROR] Failed to restore deadline of team %s: %v\n", board.TeamID, err)
+			continue
+		}
+		scheduleDeadline(teamHandler, board.ChannelID, board.Timestamp, board.Deadline)
+	}
+}
+
+// CloseAtDeadline closes the board and lets the thread know the deadline has come
+func CloseAtDeadline(handler *Handler, channelID string, timestamp string, deadline time.Time) error {
+	messageUpdateMutex.Lock()
+	defer messageUpdateMutex.Unlock()
+
+	menuBoard, err := LoadMenuBoard(handler.Client, channelID, timestamp)
+	if err != nil {
+		return err
+	}
+	if menuBoard.IsTerminated() || !menuBoard.Deadline.Equal(deadline) {
+		return nil
+	}
+	if err := CloseMenuBoard(handler, menuBoard, ""); err != nil {
+		return err
+	}
+
+	_, _, err = handler.Client.PostMessage(channelID, slack.MsgOptionText(fmt.Sprintf("⏰ %s 마감됐다옹", menuBoard.Title), false), slack.MsgOptionTS(menuBoard.ThreadTimestamp))
+	return err
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMention(t *testing.T) {
+	tests := []struct {
+		text         string
+		wantMenus    []string
+		wantDeadline *Clock
+		wantErr      bool
+	}{
+		{text: "", wantMenus: []string{}},
+		{text: "짜장면, 짬뽕 , 탕수육", wantMenus: []string{"짜장면", "짬뽕", "탕수육"}},
+		{text: "중국집", wantMenus: []string{"중국집"}},
+		{text: "마감 12:30", wantMenus: []string{}, wantDeadline: &Clock{Hour: 12, Minute: 30}},
+		{text: "짜장면, 짬뽕 마감 9:05", wantMenus: []string{"짜장면", "짬뽕"}, wantDeadline: &Clock{Hour: 9, Minute: 5}},
+		{text: "마감 18:00, 짜장면", wantMenus: []string{"짜장면"}, wantDeadline: &Clock{Hour: 18, Minute: 0}},
+		{text: "마감된 메뉴", wantMenus: []string{"마감된 메뉴"}},
+		{text: "마감", wantErr: true},
+		{text: "마감 점심", wantErr: true},
+		{text: "마감 24:00", wantErr: true},
+		{text: "마감 12:60", wantErr: true},
+		{text: "마감 12:30 마감 13:00", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			request, err := ParseMention(test.text)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("err = %v, want error %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(request.Menus, test.wantMenus) {
+				t.Errorf("menus = %q, want %q", request.Menus, test.wantMenus)
+			}
+			if !reflect.DeepEqual(request.Deadline, test.wantDeadline) {
+				t.Errorf("deadline = %v, want %v", request.Deadline, test.wantDeadline)
+			}
+		})
+	}
+}
+
+func TestClockOn(t *testing.T) {
+	seoul := time.FixedZone("KST", 9*60*60)
+	clock := &Clock{Hour: 12, Minute: 30}
+
+	// 01:00 UTC is already 10:00 in Seoul, so the deadline is 12:30 of Seoul on the same day
+	now := time.Date(2021, 6, 1, 1, 0, 0, 0, time.UTC)
+	handler := &Handler{Location: seoul, Logger: log.New(ioutil.Discard, "", 0)}
+	deadline := clock.On(handler.InLocation(now))
+	if want := time.Date(2021, 6, 1, 3, 30, 0, 0, time.UTC); !deadline.Equal(want) {
+		t.Errorf("deadline = %s, want %s", deadline, want)
+	}
+
+	// 20:00 UTC is already the next day in Seoul
+	now = time.Date(2021, 6, 1, 20, 0, 0, 0, time.UTC)
+	deadline = clock.On(handler.InLocation(now))
+	if want := time.Date(2021, 6, 2, 3, 30, 0, 0, time.UTC); !deadline.Equal(want) {
+		t.Errorf("deadline = %s, want %s", deadline, want)
+	}
+}
+
+func TestRestoreDeadlines(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+
+	// The deadline passed while the bot was down
+	deadline := time.Unix(time.Now().Add(-time.Minute).Unix(), 0)
+	menuBoard := NewMenuBoard("점심", "U1")
+	menuBoard.AddMenu("짜장면", "", "U1")
+	menuBoard.SetDeadline(deadline)
+	if err := PostMenuBoard(handler.Client, menuBoard, "C1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.Store.AddActiveBoard(ActiveBoard{TeamID: handler.TeamID, ChannelID: "C1", Timestamp: menuBoard.Timestamp, HostUserID: "U1", Deadline: deadline}); err != nil {
+		t.Fatal(err)
+	}
+
+	handler.RestoreDeadlines()
+	waitFor(t, "the board closed at the deadline", func() bool {
+		loaded, err := LoadMenuBoard(handler.Client, "C1", menuBoard.Timestamp)
+		return err == nil && loaded.IsTerminated()
+	})
+	waitFor(t, "the board removed from open boards", func() bool {
+		return len(handler.Store.ActiveBoards(handler.TeamID)) == 0
+	})
+}
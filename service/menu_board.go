@@ -4,38 +4,317 @@ import (
 	"fmt"
 	"html"
 	"slack-waiter-bot/ids"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/slack-go/slack"
 )
 
-const numHeaderBlocks = 1
-const numTailBlocks = 2
+// maxOptions is the number of options slack allows in a select menu
+const maxOptions = 100
+
+// guestAvatarURL is the placeholder avatar of guests, which is the 👤 emoji of slack
+const guestAvatarURL = "https://a.slack-edge.com/production-standard-emoji-assets/13.0/apple-large/1f464.png"
+
+// maxGuestsPerSponsor is the number of guest avatars a context block holds along with the sponsor text
+const maxGuestsPerSponsor = 9
+
+// maxGuests bounds the guests of a board, whose blocks are kept in the board message
+const maxGuests = 20
+
+// maxGuestNameLength bounds the name of a guest
+const maxGuestNameLength = 30
+
+// Guest is a chooser without slack account, whose orders are paid by the sponsor
+type Guest struct {
+	Name          string
+	SponsorUserID string
+}
+
+// ChooserName returns the name the guest chooses menus with, marked not to be confused with members
+func (g Guest) ChooserName() string {
+	return g.Name + " (손님)"
+}
+
+// Profile returns the profile the guest chooses menus with
+func (g Guest) Profile() *slack.UserProfile {
+	return &slack.UserProfile{RealName: g.ChooserName(), Image32: guestAvatarURL}
+}
 
 // Menu means a menu consist of menu select block and selcted status block
 type Menu struct {
-	MenuName        string
+	MenuName string
+	// Emoji is the reaction name which selects the menu in reaction mode
+	Emoji string
+	// CreatorUserID is who added the menu, empty for menus of old boards
+	CreatorUserID string
+	// Tags are what the menu contains, like allergens, which are checked with diets of the choosers
+	Tags            []string
 	MenuSelectBlock *slack.SectionBlock
 	StatusBlocks    []*slack.ContextBlock
 }
 
 // MenuBoard is menu board blocks containing header, menus, tail
 type MenuBoard struct {
+	Title            string
+	HostUserID       string
+	CohostUserIDs    []string
+	Guests           []Guest
+	Deadline         time.Time
+	ReactionMode     bool
 	HeaderBlocks     []slack.Block
 	TailBlocks       []slack.Block
 	Menus            []Menu
 	MenuNameIndexMap map[string]int
+
+	// Location of the board message and the continuation messages holding menus over the block limit
+	ChannelID              string
+	Timestamp              string
+	ThreadTimestamp        string
+	ParentUserID           string
+	ContinuationTimestamps []string
 }
 
 // ParseMenuBlocks parses slack menu board blocks into MenuBoard
 func ParseMenuBlocks(blocks []slack.Block) *MenuBoard {
-	numBlocks := len(blocks)
+	// Header lasts until the first menu, and tail starts from the divider after menus
+	numHeaderBlocks := 1
+	for numHeaderBlocks < len(blocks) && blocks[numHeaderBlocks].BlockType() == slack.MBTContext {
+		numHeaderBlocks++
+	}
+	tailStart := numHeaderBlocks
+	for tailStart < len(blocks) && blocks[tailStart].BlockType() != slack.MBTDivider {
+		tailStart++
+	}
 	headerBlocks := blocks[:numHeaderBlocks]
-	menuBlocks := blocks[numHeaderBlocks : numBlocks-numTailBlocks]
-	tailBlocks := blocks[numBlocks-numTailBlocks:]
+	menuBlocks := blocks[numHeaderBlocks:tailStart]
+	tailBlocks := blocks[tailStart:]
+
+	menuBoard := &MenuBoard{
+		HeaderBlocks:     headerBlocks,
+		TailBlocks:       tailBlocks,
+		Menus:            []Menu{},
+		MenuNameIndexMap: map[string]int{},
+	}
+	if headerBlock, ok := headerBlocks[0].(*slack.HeaderBlock); ok {
+		menuBoard.Title = headerBlock.Text.Text
+		if strings.HasPrefix(headerBlock.BlockID, ids.MenuHeaderBlock) {
+			menuBoard.HostUserID = strings.TrimPrefix(headerBlock.BlockID, ids.MenuHeaderBlock)
+		}
+	}
+	for _, headerBlock := range headerBlocks[1:] {
+		if contextBlock, ok := headerBlock.(*slack.ContextBlock); ok && contextBlock.BlockID == ids.ReactionModeBlock {
+			menuBoard.ReactionMode = true
+		}
+		if contextBlock, ok := headerBlock.(*slack.ContextBlock); ok && strings.HasPrefix(contextBlock.BlockID, ids.CohostBlock) {
+			menuBoard.CohostUserIDs = strings.Split(strings.TrimPrefix(contextBlock.BlockID, ids.CohostBlock), ",")
+		}
+		if contextBlock, ok := headerBlock.(*slack.ContextBlock); ok && strings.HasPrefix(contextBlock.BlockID, ids.GuestBlock) {
+			sponsorUserID := strings.TrimPrefix(contextBlock.BlockID, ids.GuestBlock)
+			for _, element := range contextBlock.ContextElements.Elements {
+				if image, ok := element.(*slack.ImageBlockElement); ok {
+					menuBoard.Guests = append(menuBoard.Guests, Guest{Name: image.AltText, SponsorUserID: sponsorUserID})
+				}
+			}
+		}
+		if contextBlock, ok := headerBlock.(*slack.ContextBlock); ok && strings.HasPrefix(contextBlock.BlockID, ids.DeadlineBlock) {
+			if unix, err := strconv.ParseInt(strings.TrimPrefix(contextBlock.BlockID, ids.DeadlineBlock), 10, 64); err == nil {
+				menuBoard.Deadline = time.Unix(unix, 0)
+			}
+		}
+	}
+	menuBoard.appendMenuBlocks(menuBlocks)
+	return menuBoard
+}
+
+// NewMenuBoard makes empty menu board with the title which is hosted by the user
+func NewMenuBoard(title string, hostUserID string) *MenuBoard {
+	headerText := slack.NewTextBlockObject("plain_text", title, false, false)
+	headerBlock := slack.NewHeaderBlock(headerText, slack.HeaderBlockOptionBlockID(ids.MenuHeaderBlock+hostUserID))
+
+	return &MenuBoard{
+		Title:            title,
+		HostUserID:       hostUserID,
+		HeaderBlocks:     []slack.Block{headerBlock},
+		TailBlocks:       []slack.Block{slack.NewDividerBlock(), newMenuButtonsBlock()},
+		Menus:            []Menu{},
+		MenuNameIndexMap: map[string]int{},
+	}
+}
+
+// newMenuButtonsBlock returns the buttons of open board
+func newMenuButtonsBlock() *slack.ActionBlock {
+	addMenuBtnTxt := slack.NewTextBlockObject("plain_text", "➕", false, false)
+	addMenuBtn := slack.NewButtonBlockElement(ids.AddMenu, ids.AddMenu, addMenuBtnTxt)
+	deleteMenuBtnTxt := slack.NewTextBlockObject("plain_text", "➖", false, false)
+	deleteMenuBtn := slack.NewButtonBlockElement(ids.DeleteMenu, ids.DeleteMenu, deleteMenuBtnTxt)
+	OrderForOtherBtnTxt := slack.NewTextBlockObject("plain_text", "👥", false, false)
+	OrderForOtherBtn := slack.NewButtonBlockElement(ids.OrderForOther, ids.OrderForOther, OrderForOtherBtnTxt)
+	terminateBtnTxt := slack.NewTextBlockObject("plain_text", "🚫", false, false)
+	terminateBtn := slack.NewButtonBlockElement(ids.TerminateMenu, ids.TerminateMenu, terminateBtnTxt).WithStyle(slack.StyleDanger)
+	terminateConfirm := slack.NewConfirmationBlockObject(
+		slack.NewTextBlockObject("plain_text", "메뉴판 마감", false, false),
+		slack.NewTextBlockObject("plain_text", "마감하면 더 이상 메뉴를 고를 수 없다옹. 정말 마감할거냐옹?", false, false),
+		slack.NewTextBlockObject("plain_text", "마감", false, false),
+		slack.NewTextBlockObject("plain_text", "취소", false, false),
+	)
+	terminateConfirm.WithStyle(slack.StyleDanger)
+	terminateBtn.Confirm = terminateConfirm
+	undoBtnTxt := slack.NewTextBlockObject("plain_text", "↩️", false, false)
+	undoBtn := slack.NewButtonBlockElement(ids.UndoBoardChange, ids.UndoBoardChange, undoBtnTxt)
+
+	return slack.NewActionBlock(ids.MenuButtonsBlock, addMenuBtn, deleteMenuBtn, OrderForOtherBtn, undoBtn, terminateBtn)
+}
+
+// Reopen brings back the buttons of the terminated board, dropping its summary and the deadline which has passed
+func (mb *MenuBoard) Reopen() {
+	if !mb.ReactionMode {
+		for _, menu := range mb.Menus {
+			menu.MenuSelectBlock.Accessory = slack.NewAccessory(newSelectMenuButton(menu.MenuName))
+		}
+	}
+	mb.TailBlocks = []slack.Block{slack.NewDividerBlock(), newMenuButtonsBlock()}
+
+	if mb.Deadline.IsZero() || mb.Deadline.After(time.Now()) {
+		return
+	}
+	headerBlocks := []slack.Block{}
+	for _, headerBlock := range mb.HeaderBlocks {
+		if contextBlock, ok := headerBlock.(*slack.ContextBlock); ok && strings.HasPrefix(contextBlock.BlockID, ids.DeadlineBlock) {
+			continue
+		}
+		headerBlocks = append(headerBlocks, headerBlock)
+	}
+	mb.HeaderBlocks = headerBlocks
+	mb.Deadline = time.Time{}
+}
+
+// SetDeadline shows the deadline under the header, replacing the previous one
+func (mb *MenuBoard) SetDeadline(deadline time.Time) {
+	headerBlocks := []slack.Block{}
+	for _, headerBlock := range mb.HeaderBlocks {
+		if contextBlock, ok := headerBlock.(*slack.ContextBlock); ok && strings.HasPrefix(contextBlock.BlockID, ids.DeadlineBlock) {
+			continue
+		}
+		headerBlocks = append(headerBlocks, headerBlock)
+	}
+
+	// Slack renders the date in the time zone of each user
+	deadlineText := slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("⏰ <!date^%d^{time}|%s> 마감이다옹", deadline.Unix(), deadline.Format("15:04")), false, false)
+	deadlineBlock := slack.NewContextBlock(ids.DeadlineBlock+strconv.FormatInt(deadline.Unix(), 10), deadlineText)
+	mb.HeaderBlocks = append(headerBlocks, deadlineBlock)
+	mb.Deadline = deadline
+}
+
+// AddCohost lets the user host the board together, showing co-hosts under the header
+func (mb *MenuBoard) AddCohost(userID string) {
+	if mb.IsHost(userID) {
+		return
+	}
+	mb.CohostUserIDs = append(mb.CohostUserIDs, userID)
+
+	headerBlocks := []slack.Block{}
+	for _, headerBlock := range mb.HeaderBlocks {
+		if contextBlock, ok := headerBlock.(*slack.ContextBlock); ok && strings.HasPrefix(contextBlock.BlockID, ids.CohostBlock) {
+			continue
+		}
+		headerBlocks = append(headerBlocks, headerBlock)
+	}
+	cohostText := slack.NewTextBlockObject("mrkdwn", "👑 같이 여는 사람: <@"+strings.Join(mb.CohostUserIDs, "> <@")+">", false, false)
+	mb.HeaderBlocks = append(headerBlocks, slack.NewContextBlock(ids.CohostBlock+strings.Join(mb.CohostUserIDs, ","), cohostText))
+}
+
+// IsHost returns whether the user is the host or a co-host of the board
+func (mb *MenuBoard) IsHost(userID string) bool {
+	// Boards made before the host was recorded are hosted by the thread starter
+	if userID == mb.HostUserID || (mb.HostUserID == "" && userID == mb.ParentUserID) {
+		return true
+	}
+	for _, cohostUserID := range mb.CohostUserIDs {
+		if userID == cohostUserID {
+			return true
+		}
+	}
+	return false
+}
+
+// AddGuest lets the guest choose menus of the board, showing guests under the header grouped by their sponsors
+func (mb *MenuBoard) AddGuest(guest Guest) {
+	mb.Guests = append(mb.Guests, guest)
+
+	headerBlocks := []slack.Block{}
+	for _, headerBlock := range mb.HeaderBlocks {
+		if contextBlock, ok := headerBlock.(*slack.ContextBlock); ok && strings.HasPrefix(contextBlock.BlockID, ids.GuestBlock) {
+			continue
+		}
+		headerBlocks = append(headerBlocks, headerBlock)
+	}
+	for _, sponsorUserID := range mb.GuestSponsors() {
+		elements := []slack.MixedElement{}
+		for _, guest := range mb.Guests {
+			if guest.SponsorUserID == sponsorUserID {
+				elements = append(elements, slack.NewImageBlockElement(guestAvatarURL, guest.Name))
+			}
+		}
+		guestText := slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("손님 %d명, <@%s>님이 낸다옹", len(elements), sponsorUserID), false, false)
+		headerBlocks = append(headerBlocks, slack.NewContextBlock(ids.GuestBlock+sponsorUserID, append(elements, guestText)...))
+	}
+	mb.HeaderBlocks = headerBlocks
+}
+
+// GuestSponsors returns sponsors of the guests in the order they are added
+func (mb *MenuBoard) GuestSponsors() []string {
+	sponsorUserIDs := []string{}
+	isAdded := map[string]bool{}
+	for _, guest := range mb.Guests {
+		if !isAdded[guest.SponsorUserID] {
+			sponsorUserIDs = append(sponsorUserIDs, guest.SponsorUserID)
+			isAdded[guest.SponsorUserID] = true
+		}
+	}
+	return sponsorUserIDs
+}
 
-	menus := []Menu{}
-	menuNameIndexMap := map[string]int{}
+// FindGuest returns the guest of the name, which can be the chooser name of the guest
+func (mb *MenuBoard) FindGuest(name string) (Guest, bool) {
+	for _, guest := range mb.Guests {
+		if guest.Name == name || guest.ChooserName() == name {
+			return guest, true
+		}
+	}
+	return Guest{}, false
+}
+
+// EnableReactionMode makes menus of the board selected by reactions on the board message instead of buttons
+func (mb *MenuBoard) EnableReactionMode() {
+	guideText := slack.NewTextBlockObject("plain_text", "👇 메뉴 옆 이모지를 이 메시지에 달아서 골라달라옹", true, false)
+	mb.HeaderBlocks = append(mb.HeaderBlocks, slack.NewContextBlock(ids.ReactionModeBlock, guideText))
+	mb.ReactionMode = true
+}
+
+// MenuNameByEmoji returns the menu selected by the reaction
+func (mb *MenuBoard) MenuNameByEmoji(emoji string) (string, bool) {
+	for _, menu := range mb.Menus {
+		if menu.Emoji != "" && menu.Emoji == emoji {
+			return menu.MenuName, true
+		}
+	}
+	return "", false
+}
+
+// IsTerminated returns whether the board is terminated and no longer has the buttons
+func (mb *MenuBoard) IsTerminated() bool {
+	for _, tailBlock := range mb.TailBlocks {
+		if tailBlock.BlockType() == slack.MBTAction {
+			return false
+		}
+	}
+	return true
+}
+
+// appendMenuBlocks parses menu select blocks and status blocks and appends them as menus
+func (mb *MenuBoard) appendMenuBlocks(menuBlocks []slack.Block) {
 	var menuSelectBlock *slack.SectionBlock
 	var statusBlocks []*slack.ContextBlock
 	for _, menuBlock := range menuBlocks {
@@ -43,38 +322,56 @@ func ParseMenuBlocks(blocks []slack.Block) *MenuBoard {
 		case *slack.SectionBlock:
 			menuBlock.Text.Text = html.UnescapeString(menuBlock.Text.Text)
 			if menuSelectBlock != nil {
-				menuName := strings.TrimSuffix(strings.TrimPrefix(statusBlocks[0].BlockID, ids.MenuSelectContextBlock), "/0")
-				menus = append(menus, Menu{
-					MenuName:        menuName,
-					MenuSelectBlock: menuSelectBlock,
-					StatusBlocks:    statusBlocks,
-				})
-				menuNameIndexMap[menuName] = len(menuNameIndexMap)
+				mb.appendMenu(menuSelectBlock, statusBlocks)
 			}
 			menuSelectBlock = menuBlock
 			statusBlocks = []*slack.ContextBlock{}
 
 		case *slack.ContextBlock:
+			// Status blocks of a menu too large for a message continue at the top of the next one
+			if menuSelectBlock == nil && len(mb.Menus) > 0 {
+				last := len(mb.Menus) - 1
+				mb.Menus[last].StatusBlocks = append(mb.Menus[last].StatusBlocks, menuBlock)
+				continue
+			}
 			statusBlocks = append(statusBlocks, menuBlock)
 		}
 	}
 
 	if menuSelectBlock != nil {
-		menuName := strings.TrimSuffix(strings.TrimPrefix(statusBlocks[0].BlockID, ids.MenuSelectContextBlock), "/0")
-		menus = append(menus, Menu{
-			MenuName:        menuName,
-			MenuSelectBlock: menuSelectBlock,
-			StatusBlocks:    statusBlocks,
-		})
-		menuNameIndexMap[menuName] = len(menuNameIndexMap)
+		mb.appendMenu(menuSelectBlock, statusBlocks)
 	}
+}
 
-	return &MenuBoard{
-		HeaderBlocks:     headerBlocks,
-		TailBlocks:       tailBlocks,
-		Menus:            menus,
-		MenuNameIndexMap: menuNameIndexMap,
+func (mb *MenuBoard) appendMenu(menuSelectBlock *slack.SectionBlock, statusBlocks []*slack.ContextBlock) {
+	menuName := strings.TrimSuffix(strings.TrimPrefix(statusBlocks[0].BlockID, ids.MenuSelectContextBlock), "/0")
+	emoji, creatorUserID := "", ""
+	// Block id is "menu_reaction_block/<emoji>/<creator>" in reaction mode, otherwise "menu_block/<menu>/<creator>"
+	if strings.HasPrefix(menuSelectBlock.BlockID, ids.MenuReactionBlock) {
+		parts := strings.SplitN(strings.TrimPrefix(menuSelectBlock.BlockID, ids.MenuReactionBlock), "/", 2)
+		emoji = parts[0]
+		if len(parts) == 2 {
+			creatorUserID = parts[1]
+		}
+	}
+	if strings.HasPrefix(menuSelectBlock.BlockID, ids.MenuBlock) {
+		creatorUserID = menuSelectBlock.BlockID[strings.LastIndex(menuSelectBlock.BlockID, "/")+1:]
+	}
+	// Tags follow the creator like "<creator>+<tag>,<tag>"
+	var tags []string
+	if i := strings.Index(creatorUserID, "+"); i >= 0 {
+		tags = strings.Split(creatorUserID[i+1:], ",")
+		creatorUserID = creatorUserID[:i]
 	}
+	mb.Menus = append(mb.Menus, Menu{
+		MenuName:        menuName,
+		Emoji:           emoji,
+		CreatorUserID:   creatorUserID,
+		Tags:            tags,
+		MenuSelectBlock: menuSelectBlock,
+		StatusBlocks:    statusBlocks,
+	})
+	mb.MenuNameIndexMap[menuName] = len(mb.MenuNameIndexMap)
 }
 
 // GetChoosers returns all persons who chose this menu
@@ -92,20 +389,65 @@ func (m *Menu) GetChoosers() []string {
 	return choosers
 }
 
-// AddMenu adds the menu
-func (mb *MenuBoard) AddMenu(menuName string, emoji string) {
+// AddMenu adds the menu added by the user, selected by the reaction of the emoji in reaction mode
+func (mb *MenuBoard) AddMenu(menuName string, emoji string, creatorUserID string) {
 	menuText := slack.NewTextBlockObject("plain_text", emoji+menuName, true, false)
-	selectText := slack.NewTextBlockObject("plain_text", "👆", false, false)
-	menuUserSelectBlock := slack.NewSectionBlock(menuText, nil, slack.NewAccessory(slack.NewButtonBlockElement(ids.SelectMenuByUser, menuName, selectText)))
+	menuUserSelectBlock := slack.NewSectionBlock(menuText, nil, slack.NewAccessory(newSelectMenuButton(menuName)), slack.SectionBlockOptionBlockID(menuBlockID(menuName, creatorUserID, nil)))
+	reaction := ""
+	if mb.ReactionMode {
+		reaction = EmojiName(emoji)
+		menuUserSelectBlock = slack.NewSectionBlock(menuText, nil, nil, slack.SectionBlockOptionBlockID(ids.MenuReactionBlock+reaction+"/"+creatorUserID))
+	}
 	menuSelectContextBlock := slack.NewContextBlock(ids.MenuSelectContextBlock+menuName+"/0", slack.NewTextBlockObject("plain_text", "0 Selected", false, false))
 	mb.Menus = append(mb.Menus, Menu{
 		MenuName:        menuName,
+		Emoji:           reaction,
+		CreatorUserID:   creatorUserID,
 		MenuSelectBlock: menuUserSelectBlock,
 		StatusBlocks:    []*slack.ContextBlock{menuSelectContextBlock},
 	})
 	mb.MenuNameIndexMap[menuName] = len(mb.MenuNameIndexMap)
 }
 
+// menuBlockID returns the block id of the menu section which keeps its name, creator and tags
+func menuBlockID(menuName string, creatorUserID string, tags []string) string {
+	blockID := ids.MenuBlock + menuName + "/" + creatorUserID
+	if len(tags) > 0 {
+		blockID += "+" + strings.Join(tags, ",")
+	}
+	return blockID
+}
+
+// SetMenuTags tags the menu with what it contains, showing the tags after the menu name
+func (mb *MenuBoard) SetMenuTags(menuName string, tags []string) {
+	menuIndex, ok := mb.MenuNameIndexMap[menuName]
+	if !ok {
+		return
+	}
+	menu := &mb.Menus[menuIndex]
+	emojiText := menu.EmojiText()
+	blockID := menu.MenuSelectBlock.BlockID
+	blockID = blockID[:strings.LastIndex(blockID, "/")+1] + menu.CreatorUserID
+	if len(tags) > 0 {
+		blockID += "+" + strings.Join(tags, ",")
+	}
+
+	menu.Tags = tags
+	menu.MenuSelectBlock.BlockID = blockID
+	menu.MenuSelectBlock.Text = slack.NewTextBlockObject("plain_text", emojiText+menuName+menuTagLabel(tags), true, false)
+}
+
+// EmojiText returns the emoji text shown before the menu name
+func (m *Menu) EmojiText() string {
+	return strings.TrimSuffix(m.MenuSelectBlock.Text.Text, m.MenuName+menuTagLabel(m.Tags))
+}
+
+// newSelectMenuButton returns the button selecting the menu
+func newSelectMenuButton(menuName string) *slack.ButtonBlockElement {
+	selectText := slack.NewTextBlockObject("plain_text", "👆", false, false)
+	return slack.NewButtonBlockElement(ids.SelectMenuByUser, menuName, selectText)
+}
+
 // DeleteMenu deletes the menu
 func (mb *MenuBoard) DeleteMenu(menuName string) {
 	menuIndex, ok := mb.MenuNameIndexMap[menuName]
@@ -123,9 +465,32 @@ func (mb *MenuBoard) DeleteMenu(menuName string) {
 
 }
 
+// RestoreMenu adds the deleted menu back at its position with its choosers
+func (mb *MenuBoard) RestoreMenu(trashed TrashedMenu) {
+	mb.AddMenu(trashed.MenuName, trashed.Emoji, trashed.CreatorUserID)
+	mb.SetMenuTags(trashed.MenuName, trashed.Tags)
+	for _, chooser := range trashed.Choosers {
+		mb.ToggleMenuByUser(&slack.UserProfile{RealName: chooser.Name, Image32: chooser.ImageURL}, trashed.MenuName)
+	}
+
+	position := trashed.Position
+	if position < 0 || position >= len(mb.Menus) {
+		return
+	}
+	restored := mb.Menus[len(mb.Menus)-1]
+	copy(mb.Menus[position+1:], mb.Menus[position:len(mb.Menus)-1])
+	mb.Menus[position] = restored
+	for i, menu := range mb.Menus {
+		mb.MenuNameIndexMap[menu.MenuName] = i
+	}
+}
+
 // ToggleMenuByUser select or unselect menu
 func (mb *MenuBoard) ToggleMenuByUser(profile *slack.UserProfile, menuName string) {
-	menuIndex := mb.MenuNameIndexMap[menuName]
+	menuIndex, ok := mb.MenuNameIndexMap[menuName]
+	if !ok {
+		return
+	}
 	statusBlocks := mb.Menus[menuIndex].StatusBlocks
 
 	isExist := false
@@ -179,15 +544,21 @@ func (mb *MenuBoard) ToBlocks() []slack.Block {
 	blocks = append(blocks, mb.HeaderBlocks...)
 
 	for _, menu := range mb.Menus {
-		blocks = append(blocks, menu.MenuSelectBlock)
-		for _, statusBlock := range menu.StatusBlocks {
-			blocks = append(blocks, statusBlock)
-		}
+		blocks = append(blocks, menu.ToBlocks()...)
 	}
 	blocks = append(blocks, mb.TailBlocks...)
 	return blocks
 }
 
+// ToBlocks make menu into menu select block and status blocks
+func (m *Menu) ToBlocks() []slack.Block {
+	blocks := []slack.Block{m.MenuSelectBlock}
+	for _, statusBlock := range m.StatusBlocks {
+		blocks = append(blocks, statusBlock)
+	}
+	return blocks
+}
+
 // ToOptionBlockObjects make into slack option block object from menu names
 func (mb *MenuBoard) ToOptionBlockObjects() []*slack.OptionBlockObject {
 	menuOptions := []*slack.OptionBlockObject{}
@@ -198,3 +569,27 @@ func (mb *MenuBoard) ToOptionBlockObjects() []*slack.OptionBlockObject {
 	}
 	return menuOptions
 }
+
+// SearchOptionBlockObjects make option block objects of menus whose name contains the query
+func (mb *MenuBoard) SearchOptionBlockObjects(query string) []*slack.OptionBlockObject {
+	query = strings.ToLower(strings.TrimSpace(query))
+	menuOptions := []*slack.OptionBlockObject{}
+	for _, menuOption := range mb.ToOptionBlockObjects() {
+		if len(menuOptions) >= maxOptions {
+			break
+		}
+		if strings.Contains(strings.ToLower(menuOption.Value), query) {
+			menuOptions = append(menuOptions, menuOption)
+		}
+	}
+	return menuOptions
+}
+
+// NewMenuExternalSelectElement returns menu select element whose options are loaded by block suggestion
+func NewMenuExternalSelectElement() *slack.SelectBlockElement {
+	minQueryLength := 0
+	placeholder := slack.NewTextBlockObject("plain_text", "메뉴 이름으로 찾아보라옹", false, false)
+	menuSelectElement := slack.NewOptionsSelectBlockElement(slack.OptTypeExternal, placeholder, ids.SubmitMenuInput)
+	menuSelectElement.MinQueryLength = &minQueryLength
+	return menuSelectElement
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minTypoQueryLength is the length of query from which a typo is tolerated
+const minTypoQueryLength = 3
+
+// MatchMenuNames finds the menus the query may mean, tolerating spacing, case and a single typo of longer names,
+// so that ordinary chat in the thread is not taken as choices. More than one name means the typo is ambiguous
+func (mb *MenuBoard) MatchMenuNames(query string) []string {
+	normalizedQuery := normalizeMenuName(query)
+	if normalizedQuery == "" {
+		return []string{}
+	}
+
+	for _, menu := range mb.Menus {
+		if normalizeMenuName(menu.MenuName) == normalizedQuery {
+			return []string{menu.MenuName}
+		}
+	}
+	if len([]rune(normalizedQuery)) < minTypoQueryLength {
+		return []string{}
+	}
+
+	matched := []string{}
+	for _, menu := range mb.Menus {
+		if editDistance(normalizeMenuName(menu.MenuName), normalizedQuery) == 1 {
+			matched = append(matched, menu.MenuName)
+		}
+	}
+	return matched
+}
+
+// HasChosen returns whether the person has chosen the menu
+func (mb *MenuBoard) HasChosen(menuName string, realName string) bool {
+	menuIndex, ok := mb.MenuNameIndexMap[menuName]
+	if !ok {
+		return false
+	}
+	for _, chooser := range mb.Menus[menuIndex].GetChoosers() {
+		if chooser == realName {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeMenuName lowers the name and drops spaces and punctuations
+func normalizeMenuName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// editDistance returns levenshtein distance of the strings in runes
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchMenuNames(t *testing.T) {
+	menuBoard := NewMenuBoard("점심", "U1")
+	for _, menuName := range []string{"짜장면", "짬뽕", "김치 볶음밥", "새우 볶음밥", "김치 덮밥", "Coke"} {
+		menuBoard.AddMenu(menuName, "", "U1")
+	}
+
+	tests := []struct {
+		query     string
+		wantNames []string
+	}{
+		{query: "짜장면", wantNames: []string{"짜장면"}},
+		{query: "김치볶음밥", wantNames: []string{"김치 볶음밥"}},
+		{query: "coke!", wantNames: []string{"Coke"}},
+		{query: "짜장먄", wantNames: []string{"짜장면"}},
+		{query: "김치볶음빱", wantNames: []string{"김치 볶음밥"}},
+		{query: "짬봉", wantNames: []string{}},
+		{query: "짜장", wantNames: []string{}},
+		{query: "볶음밥", wantNames: []string{}},
+		{query: "배고프다", wantNames: []string{}},
+		{query: "ㅋㅋ", wantNames: []string{}},
+		{query: "김치볶밥", wantNames: []string{"김치 볶음밥", "김치 덮밥"}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			if names := menuBoard.MatchMenuNames(test.query); !reflect.DeepEqual(names, test.wantNames) {
+				t.Errorf("MatchMenuNames(%q) = %q, want %q", test.query, names, test.wantNames)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// maxRequestBodyBytes limits the size of request body slack sends
+const maxRequestBodyBytes = 1 << 20
+
+// requestReplayWindow is how old a signed request can be before rejected as replayed
+const requestReplayWindow = 5 * time.Minute
+
+// VerifyRequest wraps a handler to accept only requests signed by slack with the signing secret
+func (handler *Handler) VerifyRequest(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			handler.Logger.Println("[INFO] Too large or broken request body")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		timestamp, err := strconv.ParseInt(r.Header.Get("X-Slack-Request-Timestamp"), 10, 64)
+		if err != nil {
+			handler.Logger.Println("[INFO] Request without timestamp")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if age := time.Since(time.Unix(timestamp, 0)); age > requestReplayWindow || age < -requestReplayWindow {
+			handler.Logger.Println("[INFO] Request out of replay window")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		sv, err := slack.NewSecretsVerifier(r.Header, handler.SigningSecret)
+		if err != nil {
+			handler.Logger.Println("[INFO] Request without valid signature headers")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if _, err := sv.Write(body); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if err := sv.Ensure(); err != nil {
+			handler.Logger.Println("[INFO] Request with invalid signature")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		next(w, r)
+	}
+}
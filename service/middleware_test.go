@@ -0,0 +1,86 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+// signRequest signs the body like slack does
+func signRequest(secret string, timestamp string, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyRequest(t *testing.T) {
+	body := "token=xyz&team_id=T1&command=%2Fwaiter&text=start"
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(10*time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name       string
+		body       string
+		timestamp  string
+		signature  string
+		wantStatus int
+	}{
+		{name: "valid request", body: body, timestamp: now, signature: signRequest(testSigningSecret, now, body), wantStatus: http.StatusOK},
+		{name: "signed with other secret", body: body, timestamp: now, signature: signRequest("other secret", now, body), wantStatus: http.StatusUnauthorized},
+		{name: "tampered body", body: body + "&user_id=U2", timestamp: now, signature: signRequest(testSigningSecret, now, body), wantStatus: http.StatusUnauthorized},
+		{name: "replayed stale request", body: body, timestamp: stale, signature: signRequest(testSigningSecret, stale, body), wantStatus: http.StatusUnauthorized},
+		{name: "timestamp in future", body: body, timestamp: future, signature: signRequest(testSigningSecret, future, body), wantStatus: http.StatusUnauthorized},
+		{name: "without timestamp", body: body, signature: signRequest(testSigningSecret, now, body), wantStatus: http.StatusUnauthorized},
+		{name: "without signature", body: body, timestamp: now, wantStatus: http.StatusUnauthorized},
+		{name: "too large body", body: strings.Repeat("a", maxRequestBodyBytes+1), timestamp: now, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := &Handler{SigningSecret: testSigningSecret, Logger: log.New(ioutil.Discard, "", 0)}
+			var received string
+			server := httptest.NewServer(handler.VerifyRequest(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := ioutil.ReadAll(r.Body)
+				received = string(data)
+			}))
+			defer server.Close()
+
+			request, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if test.timestamp != "" {
+				request.Header.Set("X-Slack-Request-Timestamp", test.timestamp)
+			}
+			if test.signature != "" {
+				request.Header.Set("X-Slack-Signature", test.signature)
+			}
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+
+			if response.StatusCode != test.wantStatus {
+				t.Errorf("status = %d, want %d", response.StatusCode, test.wantStatus)
+			}
+			if test.wantStatus == http.StatusOK && received != test.body {
+				t.Errorf("next handler received %q, want the signed body", received)
+			}
+			if test.wantStatus != http.StatusOK && received != "" {
+				t.Error("next handler was called for rejected request")
+			}
+		})
+	}
+}
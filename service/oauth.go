@@ -0,0 +1,104 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+const oauthStateCookie = "slack_oauth_state"
+
+// BotScopes are the bot token scopes the app requests on install
+var BotScopes = []string{
+	"app_mentions:read",
+	"channels:history",
+	"chat:write",
+	"chat:write.public",
+	"commands",
+	"emoji:read",
+	"files:write",
+	"groups:history",
+	"im:history",
+	"im:write",
+	"mpim:history",
+	"reactions:read",
+	"reactions:write",
+	"users:read",
+	"users.profile:read",
+}
+
+// Installer handles OAuth v2 install flow of other workspaces
+type Installer struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Workspaces   *WorkspaceManager
+	// HTTPClient exchanges the code with slack, http.DefaultClient when nil
+	HTTPClient *http.Client
+	Logger     *log.Logger
+}
+
+// HandleInstall redirects user to slack authorize page
+func (installer *Installer) HandleInstall(w http.ResponseWriter, r *http.Request) {
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	state := hex.EncodeToString(stateBytes)
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Value: state, Path: "/slack", MaxAge: 600, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode})
+
+	query := url.Values{
+		"client_id":    {installer.ClientID},
+		"scope":        {strings.Join(BotScopes, ",")},
+		"redirect_uri": {installer.RedirectURL},
+		"state":        {state},
+	}
+	http.Redirect(w, r, "https://slack.com/oauth/v2/authorize?"+query.Encode(), http.StatusFound)
+}
+
+// HandleOAuthRedirect exchanges the code for bot token and saves installation of the workspace
+func (installer *Installer) HandleOAuthRedirect(w http.ResponseWriter, r *http.Request) {
+	stateCookie, err := r.Cookie(oauthStateCookie)
+	if err != nil || stateCookie.Value == "" || stateCookie.Value != r.URL.Query().Get("state") {
+		installer.Logger.Println("[INFO] OAuth state mismatch")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if errorCode := r.URL.Query().Get("error"); errorCode != "" {
+		installer.Logger.Printf("[INFO] OAuth denied: %s\n", errorCode)
+		w.Write([]byte("Installation is canceled"))
+		return
+	}
+
+	httpClient := installer.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := slack.GetOAuthV2Response(httpClient, installer.ClientID, installer.ClientSecret, r.URL.Query().Get("code"), installer.RedirectURL)
+	if err != nil {
+		installer.Logger.Printf("[ERROR] OAuth access failed: %v\n", err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	installation := Installation{
+		TeamID:    response.Team.ID,
+		TeamName:  response.Team.Name,
+		BotToken:  response.AccessToken,
+		BotUserID: response.BotUserID,
+	}
+	if err := installer.Workspaces.Install(installation); err != nil {
+		installer.Logger.Printf("[ERROR] Failed to save installation: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	installer.Logger.Printf("[INFO] Installed to %s\n", installation.TeamName)
+	w.Write([]byte("Waiter Bot is installed to " + installation.TeamName))
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// redirectTransport sends requests for slack to the test server instead
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = rt.target.Scheme
+	r.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// newTestInstaller returns installer exchanging codes with the test server, which accepts only "good-code"
+func newTestInstaller(t *testing.T) (*Installer, string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/api/oauth.v2.access" || r.FormValue("client_secret") != "secret" || r.FormValue("code") != "good-code" {
+			w.Write([]byte(`{"ok":false,"error":"invalid_code"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"access_token":"xoxb-installed","bot_user_id":"UBOT2","team":{"id":"T2","name":"둘째 팀"}}`))
+	}))
+	t.Cleanup(server.Close)
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	tokenStore, err := NewTokenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	installer := &Installer{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "https://waiter.test/slack/oauth_redirect",
+		Workspaces:   &WorkspaceManager{Store: tokenStore},
+		HTTPClient:   &http.Client{Transport: redirectTransport{target: target}},
+		Logger:       log.New(ioutil.Discard, "", 0),
+	}
+	return installer, path
+}
+
+func TestHandleInstall(t *testing.T) {
+	installer, _ := newTestInstaller(t)
+
+	recorder := httptest.NewRecorder()
+	installer.HandleInstall(recorder, httptest.NewRequest(http.MethodGet, "/slack/install", nil))
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusFound)
+	}
+	location, err := url.Parse(recorder.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oauthStateCookie || !cookies[0].HttpOnly {
+		t.Fatalf("cookies = %+v, want the state cookie", cookies)
+	}
+	query := location.Query()
+	if location.Host != "slack.com" || query.Get("client_id") != "client" || query.Get("redirect_uri") != installer.RedirectURL {
+		t.Errorf("location = %s, want the authorize page of the app", location)
+	}
+	if state := query.Get("state"); state == "" || state != cookies[0].Value {
+		t.Errorf("state = %q, want the value of the cookie %q", state, cookies[0].Value)
+	}
+	if scope := query.Get("scope"); scope != strings.Join(BotScopes, ",") {
+		t.Errorf("scope = %q, want the bot scopes", scope)
+	}
+}
+
+func TestHandleOAuthRedirect(t *testing.T) {
+	tests := []struct {
+		name        string
+		noCookie    bool
+		cookie      string
+		query       string
+		wantStatus  int
+		wantInstall bool
+	}{
+		{name: "without cookie", noCookie: true, query: "state=abc&code=good-code", wantStatus: http.StatusBadRequest},
+		{name: "forged state", cookie: "abc", query: "state=xyz&code=good-code", wantStatus: http.StatusBadRequest},
+		{name: "empty state", cookie: "", query: "state=&code=good-code", wantStatus: http.StatusBadRequest},
+		{name: "denied", cookie: "abc", query: "state=abc&error=access_denied", wantStatus: http.StatusOK},
+		{name: "invalid code", cookie: "abc", query: "state=abc&code=bad-code", wantStatus: http.StatusBadGateway},
+		{name: "installed", cookie: "abc", query: "state=abc&code=good-code", wantStatus: http.StatusOK, wantInstall: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			installer, path := newTestInstaller(t)
+			request := httptest.NewRequest(http.MethodGet, "/slack/oauth_redirect?"+test.query, nil)
+			if !test.noCookie {
+				request.AddCookie(&http.Cookie{Name: oauthStateCookie, Value: test.cookie})
+			}
+			recorder := httptest.NewRecorder()
+			installer.HandleOAuthRedirect(recorder, request)
+			if recorder.Code != test.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, test.wantStatus)
+			}
+
+			// The token must survive restarts, so it is checked in the file
+			reloaded, err := NewTokenStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			installation, ok := reloaded.Get("T2")
+			if ok != test.wantInstall {
+				t.Fatalf("installed = %v, want %v", ok, test.wantInstall)
+			}
+			if ok && (installation.BotToken != "xoxb-installed" || installation.BotUserID != "UBOT2" || installation.TeamName != "둘째 팀") {
+				t.Errorf("installation = %+v, want the exchanged token", installation)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import "fmt"
+
+// maxCohosts bounds the number of co-hosts which are kept in the block id of the board
+const maxCohosts = 10
+
+// Delete policies of a channel, deciding who can delete menus added by others
+const (
+	DeletePolicyAnyone  = "anyone"
+	DeletePolicyCreator = "creator"
+	DeletePolicyHost    = "host"
+)
+
+// IsWorkspaceAdmin returns whether the user is an admin or an owner of the workspace
+func IsWorkspaceAdmin(handler *Handler, userID string) (bool, error) {
+	user, err := handler.Client.GetUserInfo(userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsAdmin || user.IsOwner || user.IsPrimaryOwner, nil
+}
+
+// CanManageBoard returns whether the user can terminate the board and change its deadline and co-hosts
+func CanManageBoard(handler *Handler, menuBoard *MenuBoard, userID string) (bool, error) {
+	if menuBoard.IsHost(userID) {
+		return true, nil
+	}
+	return IsWorkspaceAdmin(handler, userID)
+}
+
+// CanDeleteMenu returns refusal message when the delete policy of the channel does not allow the user to delete the menu, or empty string
+func CanDeleteMenu(handler *Handler, menuBoard *MenuBoard, menuName string, userID string) (string, error) {
+	policy := handler.Store.ChannelConfig(handler.TeamID, menuBoard.ChannelID).DeletePolicy
+	if policy == DeletePolicyAnyone {
+		return "", nil
+	}
+	if menuIndex, ok := menuBoard.MenuNameIndexMap[menuName]; ok && policy != DeletePolicyHost && menuBoard.Menus[menuIndex].CreatorUserID == userID {
+		return "", nil
+	}
+	canManage, err := CanManageBoard(handler, menuBoard, userID)
+	if err != nil || canManage {
+		return "", err
+	}
+	if policy == DeletePolicyHost {
+		return "이 채널에서는 메뉴판을 연 사람만 메뉴를 지울 수 있다옹", nil
+	}
+	return fmt.Sprintf("*%s*는 다른 사람이 넣은 메뉴라서 넣은 사람이나 메뉴판을 연 사람만 지울 수 있다옹", menuName), nil
+}
+
+// CanConfigureChannel returns whether the user can change the config of the channel,
+// which applies to open boards of the channel, so the user should be able to manage all of them
+func CanConfigureChannel(handler *Handler, channelID string, userID string) (bool, error) {
+	for _, activeBoard := range handler.Store.ActiveBoards(handler.TeamID) {
+		if activeBoard.ChannelID != channelID {
+			continue
+		}
+		menuBoard, err := LoadMenuBoard(handler.Client, activeBoard.ChannelID, activeBoard.Timestamp)
+		if err == ErrMenuBoardNotFound {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if menuBoard.IsTerminated() {
+			continue
+		}
+		canManage, err := CanManageBoard(handler, menuBoard, userID)
+		if err != nil || !canManage {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// newPermissionBoard posts a board in the channel hosted by U1 with U2 as co-host, U3 is an admin and U4 is anyone else
+func newPermissionBoard(t *testing.T, channelID string) (*Handler, *MenuBoard) {
+	t.Helper()
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+	fake.AddUser("U2", "같이 여는 사람")
+	fake.AddUser("U3", "관리자")
+	fake.AddUser("U4", "손님")
+	fake.SetAdmin("U3")
+
+	menuBoard := NewMenuBoard("점심", "U1")
+	menuBoard.AddCohost("U2")
+	menuBoard.AddMenu("짜장면", "", "U4")
+	menuBoard.AddMenu("짬뽕", "", "U2")
+	if err := PostNewBoard(handler, channelID, "", menuBoard); err != nil {
+		t.Fatal(err)
+	}
+	return handler, menuBoard
+}
+
+func TestIsWorkspaceAdmin(t *testing.T) {
+	handler, _ := newPermissionBoard(t, "C1")
+	for userID, want := range map[string]bool{"U1": false, "U2": false, "U3": true, "U4": false} {
+		isAdmin, err := IsWorkspaceAdmin(handler, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isAdmin != want {
+			t.Errorf("IsWorkspaceAdmin(%s) = %v, want %v", userID, isAdmin, want)
+		}
+	}
+	if _, err := IsWorkspaceAdmin(handler, "UNKNOWN"); err == nil {
+		t.Error("IsWorkspaceAdmin of unknown user returns no error")
+	}
+}
+
+func TestCanManageBoard(t *testing.T) {
+	handler, menuBoard := newPermissionBoard(t, "C1")
+	for userID, want := range map[string]bool{"U1": true, "U2": true, "U3": true, "U4": false} {
+		canManage, err := CanManageBoard(handler, menuBoard, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if canManage != want {
+			t.Errorf("CanManageBoard(%s) = %v, want %v", userID, canManage, want)
+		}
+	}
+}
+
+func TestCanDeleteMenu(t *testing.T) {
+	tests := []struct {
+		policy   string
+		menuName string
+		userID   string
+		want     string
+	}{
+		// The creator policy is the default
+		{policy: "", menuName: "짜장면", userID: "U4", want: ""},
+		{policy: "", menuName: "짬뽕", userID: "U4", want: "넣은 사람이나 메뉴판을 연 사람만 지울 수 있다옹"},
+		{policy: "", menuName: "짜장면", userID: "U1", want: ""},
+		{policy: "", menuName: "짜장면", userID: "U2", want: ""},
+		{policy: "", menuName: "짬뽕", userID: "U3", want: ""},
+		{policy: DeletePolicyCreator, menuName: "짬뽕", userID: "U4", want: "넣은 사람이나 메뉴판을 연 사람만 지울 수 있다옹"},
+		{policy: DeletePolicyAnyone, menuName: "짬뽕", userID: "U4", want: ""},
+		{policy: DeletePolicyHost, menuName: "짜장면", userID: "U4", want: "메뉴판을 연 사람만 메뉴를 지울 수 있다옹"},
+		{policy: DeletePolicyHost, menuName: "짜장면", userID: "U2", want: ""},
+		{policy: DeletePolicyHost, menuName: "짜장면", userID: "U3", want: ""},
+	}
+	for _, test := range tests {
+		handler, menuBoard := newPermissionBoard(t, "C1")
+		if err := handler.Store.SaveChannelConfig("T1", "C1", ChannelConfig{DeletePolicy: test.policy}); err != nil {
+			t.Fatal(err)
+		}
+		refusal, err := CanDeleteMenu(handler, menuBoard, test.menuName, test.userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (test.want == "") != (refusal == "") || !strings.Contains(refusal, test.want) {
+			t.Errorf("CanDeleteMenu(%q, %s) in %q policy = %q, want %q", test.menuName, test.userID, test.policy, refusal, test.want)
+		}
+	}
+}
+
+func TestCanConfigureChannel(t *testing.T) {
+	handler, _ := newPermissionBoard(t, "C1")
+	// The closed board left in the active boards does not keep anyone from configuring its channel
+	closedBoard := NewMenuBoard("저녁", "U1")
+	if err := PostNewBoard(handler, "C2", "", closedBoard); err != nil {
+		t.Fatal(err)
+	}
+	if err := CloseMenuBoard(handler, closedBoard, "U1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.Store.AddActiveBoard(NewActiveBoard("T1", closedBoard)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		channelID string
+		userID    string
+		want      bool
+	}{
+		{channelID: "C1", userID: "U1", want: true},
+		{channelID: "C1", userID: "U2", want: true},
+		{channelID: "C1", userID: "U3", want: true},
+		{channelID: "C1", userID: "U4", want: false},
+		{channelID: "C2", userID: "U4", want: true},
+		{channelID: "C3", userID: "U4", want: true},
+	}
+	for _, test := range tests {
+		canConfigure, err := CanConfigureChannel(handler, test.channelID, test.userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if canConfigure != test.want {
+			t.Errorf("CanConfigureChannel(%s, %s) = %v, want %v", test.channelID, test.userID, canConfigure, test.want)
+		}
+	}
+}
+
+func TestCohostLimit(t *testing.T) {
+	handler, menuBoard := newPermissionBoard(t, "C1")
+	replies := []string{}
+	ctx := &CommandContext{Handler: handler, ChannelID: "C1", ThreadTimestamp: menuBoard.Timestamp, UserID: "U1", Reply: func(text string, ephemeral bool) error {
+		replies = append(replies, text)
+		return nil
+	}}
+
+	// U2 is already a co-host, so the rest fill up the limit
+	mentions := ""
+	for i := 1; i < maxCohosts; i++ {
+		mentions += fmt.Sprintf("<@UC%d> ", i)
+	}
+	if err := CohostCommand(ctx, mentions); err != nil {
+		t.Fatal(err)
+	}
+	if err := CohostCommand(ctx, "<@U2> <@U1>"); err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 0 {
+		t.Fatalf("replies = %q, want no refusal up to the limit or for the hosts", replies)
+	}
+	if err := CohostCommand(ctx, "<@U4>"); err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 1 || !strings.Contains(replies[0], fmt.Sprintf("%d명까지다옹", maxCohosts)) {
+		t.Errorf("replies = %q, want refusal over the limit", replies)
+	}
+
+	loaded, err := FindThreadMenuBoard(handler, "C1", menuBoard.Timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.CohostUserIDs) != maxCohosts || loaded.IsHost("U4") {
+		t.Errorf("co-hosts = %v, want %d co-hosts without U4", loaded.CohostUserIDs, maxCohosts)
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// maxConcurrentProfileLookups limits parallel profile requests of a batched lookup
+const maxConcurrentProfileLookups = 5
+
+type cachedProfile struct {
+	profile   *slack.UserProfile
+	fetchedAt time.Time
+}
+
+// profileEntries are cached profiles shared by the profile caches of a workspace
+type profileEntries struct {
+	mutex       sync.Mutex
+	byUserID    map[string]cachedProfile
+	lastEvicted time.Time
+}
+
+// ProfileCache caches user profiles for TTL, invalidated when the user changes profile
+type ProfileCache struct {
+	Client  SlackAPI
+	TTL     time.Duration
+	entries *profileEntries
+}
+
+// NewProfileCache creates empty profile cache
+func NewProfileCache(client SlackAPI, ttl time.Duration) *ProfileCache {
+	return &ProfileCache{
+		Client:  client,
+		TTL:     ttl,
+		entries: &profileEntries{byUserID: map[string]cachedProfile{}},
+	}
+}
+
+// WithClient returns profile cache sharing the cached profiles, which fetches uncached ones with the client
+func (pc *ProfileCache) WithClient(client SlackAPI) *ProfileCache {
+	return &ProfileCache{
+		Client:  client,
+		TTL:     pc.TTL,
+		entries: pc.entries,
+	}
+}
+
+// GetProfile returns cached profile of the user or fetches it
+func (pc *ProfileCache) GetProfile(userID string) (*slack.UserProfile, error) {
+	pc.entries.mutex.Lock()
+	entry, ok := pc.entries.byUserID[userID]
+	pc.entries.mutex.Unlock()
+	if ok && time.Since(entry.fetchedAt) <= pc.TTL {
+		return entry.profile, nil
+	}
+
+	profile, err := pc.Client.GetUserProfile(&slack.GetUserProfileParameters{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	pc.entries.mutex.Lock()
+	defer pc.entries.mutex.Unlock()
+
+	// Profiles of users who are not seen again would be kept forever otherwise
+	now := time.Now()
+	if now.Sub(pc.entries.lastEvicted) > pc.TTL {
+		for cachedUserID, cached := range pc.entries.byUserID {
+			if now.Sub(cached.fetchedAt) > pc.TTL {
+				delete(pc.entries.byUserID, cachedUserID)
+			}
+		}
+		pc.entries.lastEvicted = now
+	}
+	pc.entries.byUserID[userID] = cachedProfile{profile: profile, fetchedAt: now}
+	return profile, nil
+}
+
+// GetProfiles returns profiles of the users in order, looking up uncached ones concurrently
+func (pc *ProfileCache) GetProfiles(userIDs []string) ([]*slack.UserProfile, error) {
+	profiles := make([]*slack.UserProfile, len(userIDs))
+	errs := make([]error, len(userIDs))
+	semaphore := make(chan struct{}, maxConcurrentProfileLookups)
+	var wg sync.WaitGroup
+	for i, userID := range userIDs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, userID string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			profiles[i], errs[i] = pc.GetProfile(userID)
+		}(i, userID)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return profiles, nil
+}
+
+// Invalidate drops cached profile of the user
+func (pc *ProfileCache) Invalidate(userID string) {
+	pc.entries.mutex.Lock()
+	defer pc.entries.mutex.Unlock()
+
+	delete(pc.entries.byUserID, userID)
+}
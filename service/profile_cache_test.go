@@ -0,0 +1,151 @@
+package service
+
+import (
+	"fmt"
+	"slack-waiter-bot/fakeslack"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// countingSlack counts profile lookups and how many of them run at once
+type countingSlack struct {
+	*fakeslack.Slack
+	mutex       sync.Mutex
+	calls       int
+	inFlight    int
+	maxInFlight int
+}
+
+func (s *countingSlack) GetUserProfile(params *slack.GetUserProfileParameters) (*slack.UserProfile, error) {
+	s.mutex.Lock()
+	s.calls++
+	s.inFlight++
+	if s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+	s.mutex.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	s.mutex.Lock()
+	s.inFlight--
+	s.mutex.Unlock()
+	return s.Slack.GetUserProfile(params)
+}
+
+func (s *countingSlack) numCalls() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.calls
+}
+
+func newCountingSlack() *countingSlack {
+	fake := fakeslack.New("UBOT")
+	for i := 0; i < 12; i++ {
+		fake.AddUser(fmt.Sprintf("U%d", i), fmt.Sprintf("사람%d", i))
+	}
+	return &countingSlack{Slack: fake}
+}
+
+func TestProfileCacheTTL(t *testing.T) {
+	client := newCountingSlack()
+	cache := NewProfileCache(client, 30*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		profile, err := cache.GetProfile("U1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if profile.RealName != "사람1" {
+			t.Errorf("profile = %s, want 사람1", profile.RealName)
+		}
+	}
+	if calls := client.numCalls(); calls != 1 {
+		t.Errorf("calls = %d, want the cached profile reused", calls)
+	}
+
+	cache.Invalidate("U1")
+	if _, err := cache.GetProfile("U1"); err != nil {
+		t.Fatal(err)
+	}
+	if calls := client.numCalls(); calls != 2 {
+		t.Errorf("calls = %d, want the invalidated profile fetched again", calls)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if _, err := cache.GetProfile("U1"); err != nil {
+		t.Fatal(err)
+	}
+	if calls := client.numCalls(); calls != 3 {
+		t.Errorf("calls = %d, want the expired profile fetched again", calls)
+	}
+
+	// Profiles of users not seen again are evicted when another one is cached
+	time.Sleep(40 * time.Millisecond)
+	if _, err := cache.GetProfile("U2"); err != nil {
+		t.Fatal(err)
+	}
+	cache.entries.mutex.Lock()
+	_, kept := cache.entries.byUserID["U1"]
+	numEntries := len(cache.entries.byUserID)
+	cache.entries.mutex.Unlock()
+	if kept || numEntries != 1 {
+		t.Errorf("cached %d profiles with the expired one %v, want only the new one", numEntries, kept)
+	}
+}
+
+func TestProfileCacheWithClient(t *testing.T) {
+	client := newCountingSlack()
+	other := newCountingSlack()
+	cache := NewProfileCache(client, time.Minute)
+	if _, err := cache.GetProfile("U1"); err != nil {
+		t.Fatal(err)
+	}
+
+	shared := cache.WithClient(other)
+	if _, err := shared.GetProfile("U1"); err != nil {
+		t.Fatal(err)
+	}
+	if other.numCalls() != 0 {
+		t.Error("profile cached by the original is fetched again")
+	}
+	if _, err := shared.GetProfile("U2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.GetProfile("U2"); err != nil {
+		t.Fatal(err)
+	}
+	if client.numCalls() != 1 || other.numCalls() != 1 {
+		t.Errorf("calls = %d, %d, want each profile fetched once by the cache asked first", client.numCalls(), other.numCalls())
+	}
+}
+
+func TestProfileCacheGetProfiles(t *testing.T) {
+	client := newCountingSlack()
+	cache := NewProfileCache(client, time.Minute)
+
+	userIDs := []string{}
+	for i := 11; i >= 0; i-- {
+		userIDs = append(userIDs, fmt.Sprintf("U%d", i))
+	}
+	profiles, err := cache.GetProfiles(userIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, profile := range profiles {
+		if want := fmt.Sprintf("사람%d", 11-i); profile.RealName != want {
+			t.Errorf("profiles[%d] = %s, want %s in the order of users", i, profile.RealName, want)
+		}
+	}
+	if client.maxInFlight > maxConcurrentProfileLookups || client.maxInFlight < 2 {
+		t.Errorf("looked up %d at once, want concurrently up to %d", client.maxInFlight, maxConcurrentProfileLookups)
+	}
+
+	profiles, err = cache.GetProfiles([]string{"U1", "UNKNOWN", "U2"})
+	if err == nil || profiles != nil {
+		t.Errorf("profiles = %v, err = %v, want the error of the unknown user", profiles, err)
+	}
+}
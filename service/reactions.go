@@ -0,0 +1,135 @@
+package service
+
+import (
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// PickMenuEmoji returns emoji for a new menu, which no menu or reaction of the board uses in reaction mode
+func PickMenuEmoji(handler *Handler, menuBoard *MenuBoard) string {
+	if !menuBoard.ReactionMode {
+		emoji, _ := handler.EmojiManager.GetRandomEmoji()
+		return emoji
+	}
+
+	used := map[string]bool{}
+	for _, menu := range menuBoard.Menus {
+		used[menu.Emoji] = true
+	}
+	// Reactions left from deleted menus must not select a new menu
+	if menuBoard.Timestamp != "" {
+		reactions, err := handler.Client.GetReactions(slack.NewRefToMessage(menuBoard.ChannelID, menuBoard.Timestamp), slack.NewGetReactionsParameters())
+		if err == nil {
+			for _, reaction := range reactions {
+				used[reaction.Name] = true
+			}
+		}
+	}
+	emoji, _ := handler.EmojiManager.GetRandomEmojiExcept(used)
+	return emoji
+}
+
+// AddMenuReactions reacts with the emoji of the menus on the board message so that users can click them
+func AddMenuReactions(handler *Handler, menuBoard *MenuBoard, menuNames []string) error {
+	if !menuBoard.ReactionMode {
+		return nil
+	}
+	for _, menuName := range menuNames {
+		menuIndex, ok := menuBoard.MenuNameIndexMap[menuName]
+		if !ok || menuBoard.Menus[menuIndex].Emoji == "" {
+			continue
+		}
+		if err := handler.Client.AddReaction(menuBoard.Menus[menuIndex].Emoji, slack.NewRefToMessage(menuBoard.ChannelID, menuBoard.Timestamp)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleReactionEvent selects or unselects the menu of the reaction on the board message of reaction mode
+func HandleReactionEvent(eh *Handler, userID string, reaction string, channelID string, timestamp string, added bool) error {
+	if userID == eh.BotUserID {
+		return nil
+	}
+	message, err := GetMessageFromTimeStamp(eh.Client, channelID, timestamp)
+	if err == ErrMenuBoardNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if message.User != eh.BotUserID || len(message.Blocks.BlockSet) == 0 || message.Blocks.BlockSet[0].BlockType() != slack.MBTHeader {
+		return nil
+	}
+	if menuBoard := ParseMenuBlocks(message.Blocks.BlockSet); !menuBoard.ReactionMode || menuBoard.IsTerminated() {
+		return nil
+	}
+	profile, err := eh.Profiles.GetProfile(userID)
+	if err != nil {
+		return err
+	}
+
+	messageUpdateMutex.Lock()
+	defer messageUpdateMutex.Unlock()
+
+	menuBoard, err := LoadMenuBoard(eh.Client, channelID, timestamp)
+	if err != nil {
+		return err
+	}
+	if menuBoard.IsTerminated() {
+		return nil
+	}
+	if !menuBoard.Deadline.IsZero() && time.Now().After(menuBoard.Deadline) {
+		return CloseMenuBoard(eh, menuBoard, "")
+	}
+	menuName, ok := menuBoard.MenuNameByEmoji(reaction)
+	if !ok || menuBoard.HasChosen(menuName, profile.RealName) == added {
+		return nil
+	}
+	// Reactions are not recorded to be undone, since removing the reaction undoes it
+	menuBoard.ToggleMenuByUser(profile, menuName)
+	if err := SaveOpenBoard(eh, menuBoard); err != nil {
+		return err
+	}
+	if err := AuditToggles(eh, menuBoard, userID, menuName, []string{profile.RealName}, "reaction"); err != nil {
+		return err
+	}
+	if !added {
+		return nil
+	}
+	return WarnDietConflict(eh, menuBoard, userID, menuName)
+}
+
+// SyncReactions selects menus for reactions whose events were missed, like while the server restarted
+func SyncReactions(handler *Handler, menuBoard *MenuBoard) error {
+	reactions, err := handler.Client.GetReactions(slack.NewRefToMessage(menuBoard.ChannelID, menuBoard.Timestamp), slack.NewGetReactionsParameters())
+	if err != nil {
+		return err
+	}
+	for _, reaction := range reactions {
+		menuName, ok := menuBoard.MenuNameByEmoji(reaction.Name)
+		if !ok {
+			continue
+		}
+		userIDs := []string{}
+		for _, userID := range reaction.Users {
+			if userID != handler.BotUserID {
+				userIDs = append(userIDs, userID)
+			}
+		}
+		profiles, err := handler.Profiles.GetProfiles(userIDs)
+		if err != nil {
+			return err
+		}
+		for i, profile := range profiles {
+			if !menuBoard.HasChosen(menuName, profile.RealName) {
+				menuBoard.ToggleMenuByUser(profile, menuName)
+				if err := AuditToggles(handler, menuBoard, userIDs[i], menuName, []string{profile.RealName}, "reaction"); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
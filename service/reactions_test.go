@@ -0,0 +1,135 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestReactionMode(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+	fake.AddUser("U2", "김철수")
+	fake.AddUser("U3", "박영희")
+	if err := handler.Store.SaveChannelConfig("T1", "C1", ChannelConfig{ReactionMode: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The board of reaction mode reacts with the distinct emoji of each menu
+	mentionTimestamp := fake.PostUserMessage("C1", "U1", "<@UBOT> 짜장면, 짬뽕", "")
+	if err := HandleAppMentionEvent(&slackevents.AppMentionEvent{Channel: "C1", User: "U1", Text: "<@UBOT> 짜장면, 짬뽕", TimeStamp: mentionTimestamp}, handler); err != nil {
+		t.Fatal(err)
+	}
+	menuBoard, err := FindThreadMenuBoard(handler, "C1", mentionTimestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !menuBoard.ReactionMode {
+		t.Fatal("board is not in reaction mode")
+	}
+	emoji := map[string]string{}
+	for _, menu := range menuBoard.Menus {
+		emoji[menu.MenuName] = menu.Emoji
+	}
+	if emoji["짜장면"] == "" || emoji["짜장면"] == emoji["짬뽕"] {
+		t.Fatalf("emoji = %v, want distinct emoji for each menu", emoji)
+	}
+	reactions, err := fake.GetReactions(slack.NewRefToMessage("C1", menuBoard.Timestamp), slack.GetReactionsParameters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reacted := map[string]bool{}
+	for _, reaction := range reactions {
+		reacted[reaction.Name] = reflect.DeepEqual(reaction.Users, []string{"UBOT"})
+	}
+	if !reacted[emoji["짜장면"]] || !reacted[emoji["짬뽕"]] {
+		t.Errorf("reactions = %+v, want the bot reacting with the emoji of the menus", reactions)
+	}
+
+	loaded := func() *MenuBoard {
+		loaded, err := LoadMenuBoard(fake, "C1", menuBoard.Timestamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return loaded
+	}
+	choosers := func(menuName string) []string {
+		board := loaded()
+		return board.Menus[board.MenuNameIndexMap[menuName]].GetChoosers()
+	}
+	react := func(userID string, name string, added bool) {
+		eventType, change := "reaction_removed", fake.RemoveUserReaction
+		if added {
+			eventType, change = "reaction_added", fake.AddUserReaction
+		}
+		if err := change("C1", menuBoard.Timestamp, userID, name); err != nil {
+			t.Fatal(err)
+		}
+		sendEvent(t, handler, fmt.Sprintf(`{"type":"%s","user":"%s","reaction":"%s","item_user":"UBOT","item":{"type":"message","channel":"C1","ts":"%s"},"event_ts":"1.0"}`, eventType, userID, name, menuBoard.Timestamp))
+	}
+
+	// Adding the reaction selects the menu and removing it unselects
+	react("U2", emoji["짜장면"], true)
+	waitFor(t, "the menu selected", func() bool { return reflect.DeepEqual(choosers("짜장면"), []string{"김철수"}) })
+	react("U2", emoji["짬뽕"], true)
+	waitFor(t, "the other menu selected", func() bool { return reflect.DeepEqual(choosers("짬뽕"), []string{"김철수"}) })
+	react("U2", emoji["짜장면"], false)
+	waitFor(t, "the menu unselected", func() bool { return len(choosers("짜장면")) == 0 })
+
+	// Reactions of emoji which no menu has change nothing
+	react("U2", "thumbsup", true)
+	waitFor(t, "the reaction handled", func() bool { return handler.Dispatcher.Backlog() == 0 })
+	if !reflect.DeepEqual(choosers("짬뽕"), []string{"김철수"}) || len(choosers("짜장면")) != 0 {
+		t.Errorf("choosers = %v, %v, want unchanged by other reactions", choosers("짜장면"), choosers("짬뽕"))
+	}
+
+	// Reactions whose events were missed are selected on close
+	if err := fake.AddUserReaction("C1", menuBoard.Timestamp, "U3", emoji["짜장면"]); err != nil {
+		t.Fatal(err)
+	}
+	board := loaded()
+	if err := CloseMenuBoard(handler, board, "U1"); err != nil {
+		t.Fatal(err)
+	}
+	records := handler.Store.ChannelBoards("T1", "C1")
+	if len(records) != 1 {
+		t.Fatalf("records = %d, want the closed board", len(records))
+	}
+	wantMenus := []MenuRecord{{MenuName: "짜장면", Choosers: []string{"박영희"}}, {MenuName: "짬뽕", Choosers: []string{"김철수"}}}
+	if !reflect.DeepEqual(records[0].Menus, wantMenus) {
+		t.Errorf("recorded menus = %+v, want %+v", records[0].Menus, wantMenus)
+	}
+
+	// Reactions on the closed board are ignored
+	react("U3", emoji["짬뽕"], true)
+	waitFor(t, "the reaction handled", func() bool { return handler.Dispatcher.Backlog() == 0 })
+	if got := choosers("짬뽕"); !reflect.DeepEqual(got, []string{"김철수"}) {
+		t.Errorf("choosers = %v, want the closed board unchanged", got)
+	}
+}
+
+func TestPickMenuEmoji(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+
+	menuBoard := NewMenuBoard("점심", "U1")
+	menuBoard.EnableReactionMode()
+	menuBoard.AddMenu("짜장면", ":sushi:", "U1")
+	if err := PostNewBoard(handler, "C1", "", menuBoard); err != nil {
+		t.Fatal(err)
+	}
+	// The reaction left from a deleted menu must not select a new menu
+	if err := fake.AddUserReaction("C1", menuBoard.Timestamp, "U1", "pizza"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		emoji := EmojiName(PickMenuEmoji(handler, menuBoard))
+		if emoji == "" || emoji == "sushi" || emoji == "pizza" || strings.Contains(emoji, ":") {
+			t.Fatalf("PickMenuEmoji = %q, want an emoji not used on the board", emoji)
+		}
+	}
+}
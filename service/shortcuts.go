@@ -0,0 +1,107 @@
+package service
+
+import (
+	"slack-waiter-bot/ids"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// OpenStartBoard handles when user clicks start board button in App Home or the global shortcut
+func OpenStartBoard(handler *Handler, payload *slack.InteractionCallback) error {
+	// Channel Select Block
+	channelSelectText := slack.NewTextBlockObject("plain_text", "메뉴판을 열 채널을 골라달라옹", false, false)
+	channelSelectElement := slack.NewOptionsSelectBlockElement(slack.OptTypeConversations, nil, ids.StartBoardChannel)
+	channelSelectElement.Filter = &slack.SelectBlockElementFilter{Include: []string{"public", "private"}, ExcludeBotUsers: true}
+	channelSelect := slack.NewInputBlock(ids.StartBoardChannelBlock, channelSelectText, channelSelectElement)
+
+	// Title Input Block
+	titleText := slack.NewTextBlockObject("plain_text", "메뉴판 제목", false, false)
+	titlePlaceholder := slack.NewTextBlockObject("plain_text", "ex) 점심", false, false)
+	titleElement := slack.NewPlainTextInputBlockElement(titlePlaceholder, ids.StartBoardTitle)
+	titleInput := slack.NewInputBlock(ids.StartBoardTitleBlock, titleText, titleElement)
+	titleInput.Optional = true
+
+	// Menus Input Block
+	menusText := slack.NewTextBlockObject("plain_text", "메뉴", false, false)
+	menusPlaceholder := slack.NewTextBlockObject("plain_text", "ex) 짜장면, 짬뽕", false, false)
+	menusElement := slack.NewPlainTextInputBlockElement(menusPlaceholder, ids.StartBoardMenus)
+	menusInput := slack.NewInputBlock(ids.StartBoardMenusBlock, menusText, menusElement)
+	menusInput.Optional = true
+
+	// Deadline Picker Block
+	deadlineText := slack.NewTextBlockObject("plain_text", "마감 시간", false, false)
+	deadlineElement := slack.NewTimePickerBlockElement(ids.StartBoardDeadline)
+	deadlineInput := slack.NewInputBlock(ids.StartBoardDeadlineBlock, deadlineText, deadlineElement)
+	deadlineInput.Optional = true
+
+	var modalRequest slack.ModalViewRequest
+	modalRequest.Type = slack.ViewType("modal")
+	modalRequest.Title = slack.NewTextBlockObject("plain_text", "메뉴판 열기", false, false)
+	modalRequest.Close = slack.NewTextBlockObject("plain_text", "Close", false, false)
+	modalRequest.Submit = slack.NewTextBlockObject("plain_text", "Submit", false, false)
+	modalRequest.CallbackID = ids.SubmitStartBoardCallback
+	modalRequest.Blocks = slack.Blocks{
+		BlockSet: []slack.Block{
+			channelSelect, titleInput, menusInput, deadlineInput,
+		},
+	}
+
+	_, err := handler.Client.OpenView(payload.TriggerID, modalRequest)
+	return err
+}
+
+// SubmitStartBoard handles when user submit start board view, and refreshes App Home of the user
+func SubmitStartBoard(handler *Handler, payload *slack.InteractionCallback) error {
+	channelID := payload.View.State.Values[ids.StartBoardChannelBlock][ids.StartBoardChannel].SelectedConversation
+	title := strings.TrimSpace(payload.View.State.Values[ids.StartBoardTitleBlock][ids.StartBoardTitle].Value)
+	if title == "" {
+		title = ChannelBoardTitle(handler, channelID)
+	}
+
+	menuBoard := NewChannelMenuBoard(handler, channelID, title, payload.User.ID)
+	if deadline, ok := StartBoardDeadline(handler, payload); ok {
+		menuBoard.SetDeadline(deadline)
+	}
+	for _, menuName := range StartBoardMenuNames(payload) {
+		menuBoard.AddMenu(menuName, PickMenuEmoji(handler, menuBoard), payload.User.ID)
+	}
+	if err := StartBoard(handler, channelID, "", menuBoard); err != nil {
+		return err
+	}
+	return PublishAppHome(handler, payload.User.ID)
+}
+
+// StartBoardDeadline returns the deadline picked in start board view, on today of the location of the handler
+func StartBoardDeadline(handler *Handler, payload *slack.InteractionCallback) (time.Time, bool) {
+	selectedTime := payload.View.State.Values[ids.StartBoardDeadlineBlock][ids.StartBoardDeadline].SelectedTime
+	if selectedTime == "" {
+		return time.Time{}, false
+	}
+	clock, err := ParseClock(selectedTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return clock.On(handler.Now()), true
+}
+
+// StartBoardMenuNames returns the comma separated menus written in start board view
+func StartBoardMenuNames(payload *slack.InteractionCallback) []string {
+	return SplitMenuNames(payload.View.State.Values[ids.StartBoardMenusBlock][ids.StartBoardMenus].Value)
+}
+
+// StartBoardHere handles the message shortcut which attaches a board to the thread of the message
+func StartBoardHere(handler *Handler, payload *slack.InteractionCallback) error {
+	threadTimestamp := payload.Message.ThreadTimestamp
+	if threadTimestamp == "" {
+		threadTimestamp = payload.Message.Timestamp
+	}
+
+	menuBoard := NewChannelMenuBoard(handler, payload.Channel.ID, ChannelBoardTitle(handler, payload.Channel.ID), payload.User.ID)
+	err := StartBoard(handler, payload.Channel.ID, threadTimestamp, menuBoard)
+	if err == ErrBoardAlreadyExists {
+		_, err = handler.Client.PostEphemeral(payload.Channel.ID, payload.User.ID, slack.MsgOptionText("이 스레드에는 이미 메뉴판이 있다옹", false), slack.MsgOptionTS(threadTimestamp))
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"slack-waiter-bot/ids"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startBoardSubmission returns the payload of the user submitting the start board view
+func startBoardSubmission(userID string, channelID string, title string, menus string, deadline string) string {
+	return fmt.Sprintf(`{"type":"view_submission","trigger_id":"%s/start/%d","team":{"id":"T1"},"user":{"id":"%s"},"view":{"callback_id":"%s","state":{"values":{
+		"%s":{"%s":{"type":"conversations_select","selected_conversation":%q}},
+		"%s":{"%s":{"type":"plain_text_input","value":%q}},
+		"%s":{"%s":{"type":"plain_text_input","value":%q}},
+		"%s":{"%s":{"type":"timepicker","selected_time":%q}}}}}}`,
+		userID, time.Now().UnixNano(), userID, ids.SubmitStartBoardCallback,
+		ids.StartBoardChannelBlock, ids.StartBoardChannel, channelID,
+		ids.StartBoardTitleBlock, ids.StartBoardTitle, title,
+		ids.StartBoardMenusBlock, ids.StartBoardMenus, menus,
+		ids.StartBoardDeadlineBlock, ids.StartBoardDeadline, deadline)
+}
+
+func TestStartBoardShortcut(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+
+	// The global shortcut opens the start board view
+	sendAction(t, handler, fmt.Sprintf(`{"type":"shortcut","callback_id":"%s","trigger_id":"U1/shortcut","team":{"id":"T1"},"user":{"id":"U1"}}`, ids.StartBoardShortcut))
+	waitFor(t, "the start board view opened", func() bool { return len(fake.Views()) == 1 })
+	if view := fake.Views()[0]; view.CallbackID != ids.SubmitStartBoardCallback {
+		t.Errorf("callback id = %q, want %q", view.CallbackID, ids.SubmitStartBoardCallback)
+	}
+
+	// Invalid menus are reported on the menus input without posting a board
+	for _, menus := range []string{"짜장면, 짜장면", "짜장/면", strings.Repeat("면", maxMenuNameLength+1)} {
+		if response := sendAction(t, handler, startBoardSubmission("U1", "C2", "", menus, "")); !strings.Contains(response, ids.StartBoardMenusBlock) {
+			t.Errorf("response of menus %q = %q, want error on the menus input", menus, response)
+		}
+	}
+	if response := sendAction(t, handler, startBoardSubmission("U1", "C2", "", "", "00:00")); !strings.Contains(response, ids.StartBoardDeadlineBlock) && handler.Now().Format("15:04") != "00:00" {
+		t.Errorf("response of past deadline = %q, want error on the deadline picker", response)
+	}
+	if messages := fake.Messages("C2"); len(messages) != 0 {
+		t.Fatalf("messages = %d, want no board posted by invalid views", len(messages))
+	}
+
+	// Valid view posts the board with the title and menus in the channel
+	if response := sendAction(t, handler, startBoardSubmission("U1", "C2", "저녁", " 짜장면, 짬뽕 ,", "")); response != "" {
+		t.Errorf("response of valid view = %q, want empty to close the view", response)
+	}
+	var menuBoard *MenuBoard
+	waitFor(t, "the board posted", func() bool {
+		activeBoards := handler.Store.ActiveBoards("T1")
+		if len(activeBoards) != 1 {
+			return false
+		}
+		loaded, err := LoadMenuBoard(fake, activeBoards[0].ChannelID, activeBoards[0].Timestamp)
+		if err != nil {
+			return false
+		}
+		menuBoard = loaded
+		return true
+	})
+	if activeBoard := handler.Store.ActiveBoards("T1")[0]; activeBoard.ChannelID != "C2" || activeBoard.Title != "저녁" || activeBoard.HostUserID != "U1" {
+		t.Errorf("active board = %+v, want the board of U1 titled 저녁 in C2", activeBoard)
+	}
+	menuNames := []string{}
+	for _, menu := range menuBoard.Menus {
+		menuNames = append(menuNames, menu.MenuName)
+	}
+	if !reflect.DeepEqual(menuNames, []string{"짜장면", "짬뽕"}) {
+		t.Errorf("menus = %v, want the menus written in the view", menuNames)
+	}
+	waitFor(t, "the home refreshed", func() bool {
+		_, ok := fake.HomeView("U1")
+		return ok
+	})
+}
+
+func TestStartBoardHereShortcut(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+	messageTimestamp := fake.PostUserMessage("C1", "U1", "점심 먹을 사람", "")
+	shortcut := fmt.Sprintf(`{"type":"message_action","callback_id":"%s","trigger_id":"U1/here/%%d","team":{"id":"T1"},"user":{"id":"U1"},"channel":{"id":"C1"},"message":{"ts":"%s"}}`, ids.StartBoardHereShortcut, messageTimestamp)
+
+	// The message shortcut opens the board in the thread of the message
+	sendAction(t, handler, fmt.Sprintf(shortcut, 1))
+	waitFor(t, "the board posted", func() bool { return len(handler.Store.ActiveBoards("T1")) == 1 })
+	activeBoard := handler.Store.ActiveBoards("T1")[0]
+	if _, err := LoadMenuBoard(fake, "C1", activeBoard.Timestamp); err != nil {
+		t.Fatal(err)
+	}
+	if activeBoard.ChannelID != "C1" || activeBoard.Timestamp == messageTimestamp {
+		t.Errorf("active board = %+v, want a new board in C1", activeBoard)
+	}
+
+	// The second board in the same thread is refused
+	sendAction(t, handler, fmt.Sprintf(shortcut, 2))
+	waitFor(t, "the refusal", func() bool {
+		for _, ephemeral := range fake.Ephemerals() {
+			if ephemeral.UserID == "U1" && strings.Contains(ephemeral.Text, "이미 메뉴판이 있다옹") {
+				return true
+			}
+		}
+		return false
+	})
+	if activeBoards := handler.Store.ActiveBoards("T1"); len(activeBoards) != 1 {
+		t.Errorf("active boards = %d, want one board in the thread", len(activeBoards))
+	}
+}
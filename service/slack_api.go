@@ -0,0 +1,25 @@
+package service
+
+import "github.com/slack-go/slack"
+
+// SlackAPI is the part of slack web api the bot uses, implemented by slack.Client and RetryClient
+type SlackAPI interface {
+	PostMessage(channelID string, options ...slack.MsgOption) (string, string, error)
+	PostEphemeral(channelID, userID string, options ...slack.MsgOption) (string, error)
+	UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error)
+	DeleteMessage(channelID, timestamp string) (string, string, error)
+	OpenView(triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error)
+	PublishView(userID string, view slack.HomeTabViewRequest, hash string) (*slack.ViewResponse, error)
+	GetPermalink(params *slack.PermalinkParameters) (string, error)
+	GetUserProfile(params *slack.GetUserProfileParameters) (*slack.UserProfile, error)
+	GetUserInfo(userID string) (*slack.User, error)
+	GetConversationReplies(params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error)
+	GetEmoji() (map[string]string, error)
+	OpenConversation(params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error)
+	UploadFile(params slack.FileUploadParameters) (*slack.File, error)
+	AddReaction(name string, item slack.ItemRef) error
+	GetReactions(item slack.ItemRef, params slack.GetReactionsParameters) ([]slack.ItemReaction, error)
+}
+
+var _ SlackAPI = (*slack.Client)(nil)
+var _ SlackAPI = (*RetryClient)(nil)
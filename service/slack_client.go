@@ -0,0 +1,230 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const defaultMaxRetries = 3
+const defaultRetryBaseDelay = 500 * time.Millisecond
+const defaultRetryMaxDelay = 10 * time.Second
+
+// RetryClient wraps slack api to retry rate limited and transient failures of the calls the bot uses
+type RetryClient struct {
+	API        SlackAPI
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	Logger     *log.Logger
+}
+
+// NewRetryClient wraps slack api with default retry policy
+func NewRetryClient(api SlackAPI, logger *log.Logger) *RetryClient {
+	return &RetryClient{
+		API:        api,
+		MaxRetries: defaultMaxRetries,
+		BaseDelay:  defaultRetryBaseDelay,
+		MaxDelay:   defaultRetryMaxDelay,
+		Logger:     logger,
+	}
+}
+
+// retry calls the function until it succeeds, fails permanently or runs out of retries
+func (c *RetryClient) retry(name string, call func() error) error {
+	return c.retryCall(name, true, call)
+}
+
+// retryRateLimited retries the call only when it is rate limited, for calls which are not idempotent
+// since a transport error may come after slack has applied the call, like posting the message twice
+func (c *RetryClient) retryRateLimited(name string, call func() error) error {
+	return c.retryCall(name, false, call)
+}
+
+func (c *RetryClient) retryCall(name string, retryTransient bool, call func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil {
+			return nil
+		}
+
+		delay, retryable := c.retryDelay(err, attempt, retryTransient)
+		if !retryable || attempt >= c.MaxRetries {
+			return err
+		}
+		c.Logger.Printf("[INFO] Retrying %s in %s: %v\n", name, delay, err)
+		time.Sleep(delay)
+	}
+}
+
+// retryDelay returns how long to wait before retrying and whether the error can be retried
+func (c *RetryClient) retryDelay(err error, attempt int, retryTransient bool) (time.Duration, bool) {
+	// Callers may hold messageUpdateMutex while sleeping, so retry-after of slack is bounded too
+	var rateLimitedError *slack.RateLimitedError
+	if errors.As(err, &rateLimitedError) {
+		delay := rateLimitedError.RetryAfter
+		if delay > c.MaxDelay {
+			delay = c.MaxDelay
+		}
+		return delay + jitter(c.BaseDelay), true
+	}
+	if !retryTransient {
+		return 0, false
+	}
+
+	var retryableError interface{ Retryable() bool }
+	var netError net.Error
+	if !(errors.As(err, &retryableError) && retryableError.Retryable()) && !errors.As(err, &netError) {
+		return 0, false
+	}
+
+	delay := c.BaseDelay << uint(attempt)
+	if delay <= 0 || delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	return delay/2 + jitter(delay/2), true
+}
+
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// PostMessage posts message, retried only when rate limited
+func (c *RetryClient) PostMessage(channelID string, options ...slack.MsgOption) (respChannel string, respTimestamp string, err error) {
+	err = c.retryRateLimited("PostMessage", func() error {
+		respChannel, respTimestamp, err = c.API.PostMessage(channelID, options...)
+		return err
+	})
+	return
+}
+
+// PostEphemeral posts ephemeral message, retried only when rate limited
+func (c *RetryClient) PostEphemeral(channelID, userID string, options ...slack.MsgOption) (respTimestamp string, err error) {
+	err = c.retryRateLimited("PostEphemeral", func() error {
+		respTimestamp, err = c.API.PostEphemeral(channelID, userID, options...)
+		return err
+	})
+	return
+}
+
+// UpdateMessage updates message with retries
+func (c *RetryClient) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (respChannel string, respTimestamp string, text string, err error) {
+	err = c.retry("UpdateMessage", func() error {
+		respChannel, respTimestamp, text, err = c.API.UpdateMessage(channelID, timestamp, options...)
+		return err
+	})
+	return
+}
+
+// DeleteMessage deletes message with retries
+func (c *RetryClient) DeleteMessage(channelID, timestamp string) (respChannel string, respTimestamp string, err error) {
+	err = c.retry("DeleteMessage", func() error {
+		respChannel, respTimestamp, err = c.API.DeleteMessage(channelID, timestamp)
+		return err
+	})
+	return
+}
+
+// PublishView publishes home tab view of the user with retries
+func (c *RetryClient) PublishView(userID string, view slack.HomeTabViewRequest, hash string) (response *slack.ViewResponse, err error) {
+	err = c.retry("PublishView", func() error {
+		response, err = c.API.PublishView(userID, view, hash)
+		return err
+	})
+	return
+}
+
+// GetPermalink returns permalink of the message with retries
+func (c *RetryClient) GetPermalink(params *slack.PermalinkParameters) (permalink string, err error) {
+	err = c.retry("GetPermalink", func() error {
+		permalink, err = c.API.GetPermalink(params)
+		return err
+	})
+	return
+}
+
+// AddReaction adds reaction to the item with retries
+func (c *RetryClient) AddReaction(name string, item slack.ItemRef) error {
+	return c.retry("AddReaction", func() error {
+		return c.API.AddReaction(name, item)
+	})
+}
+
+// GetReactions returns reactions of the item with retries
+func (c *RetryClient) GetReactions(item slack.ItemRef, params slack.GetReactionsParameters) (reactions []slack.ItemReaction, err error) {
+	err = c.retry("GetReactions", func() error {
+		reactions, err = c.API.GetReactions(item, params)
+		return err
+	})
+	return
+}
+
+// OpenView opens view with retries
+func (c *RetryClient) OpenView(triggerID string, view slack.ModalViewRequest) (response *slack.ViewResponse, err error) {
+	err = c.retry("OpenView", func() error {
+		response, err = c.API.OpenView(triggerID, view)
+		return err
+	})
+	return
+}
+
+// GetUserProfile gets user profile with retries
+func (c *RetryClient) GetUserProfile(params *slack.GetUserProfileParameters) (profile *slack.UserProfile, err error) {
+	err = c.retry("GetUserProfile", func() error {
+		profile, err = c.API.GetUserProfile(params)
+		return err
+	})
+	return
+}
+
+// GetUserInfo gets user with retries
+func (c *RetryClient) GetUserInfo(userID string) (user *slack.User, err error) {
+	err = c.retry("GetUserInfo", func() error {
+		user, err = c.API.GetUserInfo(userID)
+		return err
+	})
+	return
+}
+
+// GetConversationReplies gets thread messages with retries
+func (c *RetryClient) GetConversationReplies(params *slack.GetConversationRepliesParameters) (messages []slack.Message, hasMore bool, nextCursor string, err error) {
+	err = c.retry("GetConversationReplies", func() error {
+		messages, hasMore, nextCursor, err = c.API.GetConversationReplies(params)
+		return err
+	})
+	return
+}
+
+// GetEmoji gets custom emoji of workspace with retries
+func (c *RetryClient) GetEmoji() (emoji map[string]string, err error) {
+	err = c.retry("GetEmoji", func() error {
+		emoji, err = c.API.GetEmoji()
+		return err
+	})
+	return
+}
+
+// OpenConversation opens direct message with retries
+func (c *RetryClient) OpenConversation(params *slack.OpenConversationParameters) (channel *slack.Channel, noOp bool, alreadyOpen bool, err error) {
+	err = c.retry("OpenConversation", func() error {
+		channel, noOp, alreadyOpen, err = c.API.OpenConversation(params)
+		return err
+	})
+	return
+}
+
+// UploadFile uploads file, retried only when rate limited
+func (c *RetryClient) UploadFile(params slack.FileUploadParameters) (file *slack.File, err error) {
+	err = c.retryRateLimited("UploadFile", func() error {
+		file, err = c.API.UploadFile(params)
+		return err
+	})
+	return
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// newFakeSlackAPIServer serves slack web api by failing the first failures calls with the failure, then succeeding
+func newFakeSlackAPIServer(t *testing.T, failures int32, failure string) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			switch failure {
+			case "rate_limited":
+				// Retry-After far longer than the test runs, which must be capped
+				w.Header().Set("Retry-After", "3600")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			case "transport":
+				conn, _, err := w.(http.Hijacker).Hijack()
+				if err != nil {
+					t.Fatal(err)
+				}
+				conn.Close()
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"channel":"C1","ts":"1600000000.000001","text":"menu"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newTestRetryClient(server *httptest.Server) *RetryClient {
+	client := NewRetryClient(slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/")), log.New(ioutil.Discard, "", 0))
+	client.BaseDelay = time.Millisecond
+	client.MaxDelay = 10 * time.Millisecond
+	return client
+}
+
+func TestRetryClient(t *testing.T) {
+	post := func(client *RetryClient) error {
+		_, _, err := client.PostMessage("C1", slack.MsgOptionText("menu", false))
+		return err
+	}
+	ephemeral := func(client *RetryClient) error {
+		_, err := client.PostEphemeral("C1", "U1", slack.MsgOptionText("menu", false))
+		return err
+	}
+	update := func(client *RetryClient) error {
+		_, _, _, err := client.UpdateMessage("C1", "1600000000.000001", slack.MsgOptionText("menu", false))
+		return err
+	}
+
+	tests := []struct {
+		name      string
+		call      func(client *RetryClient) error
+		failures  int32
+		failure   string
+		wantCalls int32
+		wantErr   bool
+	}{
+		{name: "rate limited post is retried", call: post, failures: 1, failure: "rate_limited", wantCalls: 2},
+		{name: "rate limited update is retried", call: update, failures: 2, failure: "rate_limited", wantCalls: 3},
+		{name: "rate limit gives up after max retries", call: update, failures: 10, failure: "rate_limited", wantCalls: defaultMaxRetries + 1, wantErr: true},
+		{name: "post is not retried on transport error", call: post, failures: 1, failure: "transport", wantCalls: 1, wantErr: true},
+		{name: "ephemeral is not retried on transport error", call: ephemeral, failures: 1, failure: "transport", wantCalls: 1, wantErr: true},
+		{name: "update is retried on transport error", call: update, failures: 1, failure: "transport", wantCalls: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, calls := newFakeSlackAPIServer(t, test.failures, test.failure)
+			client := newTestRetryClient(server)
+
+			start := time.Now()
+			err := test.call(client)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("err = %v, want error %v", err, test.wantErr)
+			}
+			if got := atomic.LoadInt32(calls); got != test.wantCalls {
+				t.Errorf("calls = %d, want %d", got, test.wantCalls)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("took %s, retry-after should be capped by max delay", elapsed)
+			}
+		})
+	}
+}
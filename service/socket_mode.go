@@ -0,0 +1,52 @@
+package service
+
+import (
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// RunSocketMode receives events and interactions over socket mode websocket instead of public http endpoints
+func (handler *Handler) RunSocketMode(socketClient *socketmode.Client) error {
+	go handler.HandleSocketModeEvents(socketClient)
+	return socketClient.Run()
+}
+
+// HandleSocketModeEvents acknowledges socket mode envelopes and dispatches them to the same handlers as http endpoints
+func (handler *Handler) HandleSocketModeEvents(socketClient *socketmode.Client) {
+	for evt := range socketClient.Events {
+		switch evt.Type {
+		case socketmode.EventTypeConnecting:
+			handler.Logger.Println("[INFO] Connecting to socket mode")
+		case socketmode.EventTypeConnected:
+			handler.Logger.Println("[INFO] Socket mode connected")
+		case socketmode.EventTypeConnectionError:
+			handler.Logger.Println("[ERROR] Socket mode connection failed, retrying")
+		// Every envelope is acknowledged, otherwise slack redelivers it
+		case socketmode.EventTypeEventsAPI:
+			socketClient.Ack(*evt.Request)
+			eventsAPIEvent, ok := evt.Data.(slackevents.EventsAPIEvent)
+			if !ok {
+				handler.Logger.Println("[INFO] Bad socket mode event")
+				continue
+			}
+			handler.DispatchEvent(eventsAPIEvent)
+		case socketmode.EventTypeInteractive:
+			payload, ok := evt.Data.(slack.InteractionCallback)
+			if !ok {
+				handler.Logger.Println("[INFO] Bad socket mode interaction")
+				socketClient.Ack(*evt.Request)
+				continue
+			}
+			socketClient.Ack(*evt.Request, handler.DispatchAction(&payload))
+		case socketmode.EventTypeSlashCommand:
+			socketClient.Ack(*evt.Request)
+			command, ok := evt.Data.(slack.SlashCommand)
+			if !ok {
+				handler.Logger.Println("[INFO] Bad socket mode slash command")
+				continue
+			}
+			handler.DispatchCommand(command)
+		}
+	}
+}
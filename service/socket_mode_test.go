@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// socketModeEnvelopes are sent after hello, one of each kind the bot receives
+var socketModeEnvelopes = []string{
+	`{"type":"events_api","envelope_id":"event-1","accepts_response_payload":false,"payload":{
+		"type":"event_callback","team_id":"T1","event_id":"Ev1",
+		"event":{"type":"user_change","user":{"id":"U1","name":"user"}}}}`,
+	`{"type":"interactive","envelope_id":"interactive-1","accepts_response_payload":true,"payload":{
+		"type":"view_submission","trigger_id":"trigger-1","team":{"id":"T1"},"user":{"id":"U1"},
+		"view":{"callback_id":"submit_start_board_callback","state":{"values":{
+			"start_board_deadline_block":{"start_board_deadline":{"type":"timepicker","selected_time":"00:00"}}}}}}}`,
+	`{"type":"slash_commands","envelope_id":"command-1","accepts_response_payload":true,"payload":{
+		"command":"/waiter","text":"help","team_id":"T1","channel_id":"C1","user_id":"U1","response_url":"RESPONSE_URL"}}`,
+}
+
+func TestHandleSocketModeEvents(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	acks := make(chan socketmode.Response, len(socketModeEnvelopes))
+	replies := make(chan string, 1)
+	// The client sends the origin of slack api, not of the test server
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"url":"ws` + strings.TrimPrefix(server.URL, "http") + `/link"}`))
+	})
+	mux.HandleFunc("/response", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		replies <- string(body)
+	})
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"hello","num_connections":1}`)); err != nil {
+			t.Error(err)
+			return
+		}
+		for _, envelope := range socketModeEnvelopes {
+			envelope = strings.Replace(envelope, "RESPONSE_URL", server.URL+"/response", 1)
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(envelope)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+		for range socketModeEnvelopes {
+			var ack socketmode.Response
+			if err := conn.ReadJSON(&ack); err != nil {
+				return
+			}
+			acks <- ack
+		}
+		// Keep the connection until the client is stopped
+		conn.ReadMessage()
+	})
+
+	api := slack.New("xoxb-test", slack.OptionAppLevelToken("xapp-test"), slack.OptionAPIURL(server.URL+"/"))
+	socketClient := socketmode.New(api)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handler.HandleSocketModeEvents(socketClient)
+	go socketClient.RunContext(ctx)
+
+	received := map[string]socketmode.Response{}
+	timeout := time.After(5 * time.Second)
+	for len(received) < len(socketModeEnvelopes) {
+		select {
+		case ack := <-acks:
+			received[ack.EnvelopeID] = ack
+		case <-timeout:
+			t.Fatalf("acknowledged %d envelopes, want %d", len(received), len(socketModeEnvelopes))
+		}
+	}
+	for _, envelopeID := range []string{"event-1", "interactive-1", "command-1"} {
+		if _, ok := received[envelopeID]; !ok {
+			t.Errorf("envelope %s is not acknowledged", envelopeID)
+		}
+	}
+
+	// The response of the view submission is sent with its ack
+	payload, err := json.Marshal(received["interactive-1"].Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(payload), `"response_action":"errors"`) || !strings.Contains(string(payload), "start_board_deadline_block") {
+		t.Errorf("ack payload = %s, want errors of the past deadline", payload)
+	}
+
+	select {
+	case reply := <-replies:
+		if !strings.Contains(reply, "waiter") {
+			t.Errorf("reply = %s, want usage of the command", reply)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("slash command is not answered")
+	}
+}
@@ -0,0 +1,640 @@
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// maxBoardRecords bounds the number of terminated boards kept in the store
+const maxBoardRecords = 5000
+
+// maxActiveBoards bounds the number of open boards kept in the store, dropping boards never closed
+const maxActiveBoards = 1000
+
+// maxOperations bounds the number of board operations kept to be undone
+const maxOperations = 5000
+
+// maxOperationsPerBoard bounds the number of operations of a board, dropping its oldest ones
+const maxOperationsPerBoard = 50
+
+// trashTTL is how long deleted menus are kept to be restored
+const trashTTL = 30 * time.Minute
+
+// MenuRecord is a menu and its choosers of a terminated board
+type MenuRecord struct {
+	MenuName string   `json:"menu_name"`
+	Choosers []string `json:"choosers"`
+}
+
+// BoardRecord is a terminated board kept for history and stats
+type BoardRecord struct {
+	TeamID       string       `json:"team_id"`
+	ChannelID    string       `json:"channel_id"`
+	Timestamp    string       `json:"timestamp"`
+	Title        string       `json:"title"`
+	HostUserID   string       `json:"host_user_id"`
+	TerminatedAt time.Time    `json:"terminated_at"`
+	Menus        []MenuRecord `json:"menus"`
+	// Guests maps chooser names of guests to the sponsors paying for them
+	Guests map[string]string `json:"guests,omitempty"`
+}
+
+// ActiveBoard is an open board listed in App Home
+type ActiveBoard struct {
+	TeamID     string    `json:"team_id"`
+	ChannelID  string    `json:"channel_id"`
+	Timestamp  string    `json:"timestamp"`
+	Title      string    `json:"title"`
+	HostUserID string    `json:"host_user_id"`
+	StartedAt  time.Time `json:"started_at"`
+	// ThreadTimestamp is the thread the board is in, the same as the timestamp for boards starting threads
+	ThreadTimestamp string `json:"thread_timestamp,omitempty"`
+	// Deadline is kept to schedule closing the board again after restart
+	Deadline time.Time `json:"deadline,omitempty"`
+	// Permalink, co-hosts and menus are kept to list the board in App Home without loading it
+	Permalink     string       `json:"permalink,omitempty"`
+	CohostUserIDs []string     `json:"cohost_user_ids,omitempty"`
+	Menus         []MenuRecord `json:"menus,omitempty"`
+}
+
+// Picks returns the menus the person has chosen on the open board
+func (board ActiveBoard) Picks(realName string) []string {
+	picks := []string{}
+	for _, menu := range board.Menus {
+		for _, chooser := range menu.Choosers {
+			if chooser == realName {
+				picks = append(picks, menu.MenuName)
+				break
+			}
+		}
+	}
+	return picks
+}
+
+// IsHost returns whether the user hosts the open board, alone or as a co-host
+func (board ActiveBoard) IsHost(userID string) bool {
+	if board.HostUserID == userID {
+		return true
+	}
+	for _, cohostUserID := range board.CohostUserIDs {
+		if cohostUserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// TrashedChooser is a chooser of a deleted menu with the avatar shown on the board
+type TrashedChooser struct {
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url"`
+}
+
+// TrashedMenu is a deleted menu kept for a while so that the host can restore it with its choosers
+type TrashedMenu struct {
+	TeamID    string `json:"team_id"`
+	ChannelID string `json:"channel_id"`
+	Timestamp string `json:"timestamp"`
+	MenuName  string `json:"menu_name"`
+	// Position is the index of the menu on the board it is restored at
+	Position int `json:"position"`
+	// Emoji is the emoji text shown before the menu name
+	Emoji         string           `json:"emoji"`
+	CreatorUserID string           `json:"creator_user_id"`
+	Tags          []string         `json:"tags,omitempty"`
+	Choosers      []TrashedChooser `json:"choosers"`
+	DeletedBy     string           `json:"deleted_by"`
+	DeletedAt     time.Time        `json:"deleted_at"`
+}
+
+// Kinds of board operations which can be undone
+const (
+	OperationToggle    = "toggle"
+	OperationAdd       = "add"
+	OperationDelete    = "delete"
+	OperationTerminate = "terminate"
+)
+
+// BoardOperation is a change applied to a board, kept so that the last one can be undone
+type BoardOperation struct {
+	TeamID    string `json:"team_id"`
+	ChannelID string `json:"channel_id"`
+	Timestamp string `json:"timestamp"`
+	Kind      string `json:"kind"`
+	// UserID is who made the change, empty for changes made by the bot like closing at the deadline
+	UserID    string   `json:"user_id"`
+	MenuNames []string `json:"menu_names,omitempty"`
+	// Choosers are the users whose choice of the menu is toggled
+	Choosers []TrashedChooser `json:"choosers,omitempty"`
+	// Trashed is the deleted menu
+	Trashed *TrashedMenu `json:"trashed,omitempty"`
+	At      time.Time    `json:"at"`
+}
+
+// ChannelConfig is the settings of the bot in a channel
+type ChannelConfig struct {
+	Title   string `json:"title,omitempty"`
+	NoQuote bool   `json:"no_quote,omitempty"`
+	// ReactionMode makes boards of the channel selected by reactions
+	ReactionMode bool `json:"reaction_mode,omitempty"`
+	// DeletePolicy decides who can delete menus added by others, DeletePolicyCreator when empty
+	DeletePolicy string `json:"delete_policy,omitempty"`
+}
+
+// DietProfile is the dietary restrictions of a user
+type DietProfile struct {
+	Restrictions []string `json:"restrictions"`
+}
+
+type storeData struct {
+	Boards         []BoardRecord                  `json:"boards"`
+	ActiveBoards   []ActiveBoard                  `json:"active_boards"`
+	Templates      map[string]map[string][]string `json:"templates"`
+	ChannelConfigs map[string]ChannelConfig       `json:"channel_configs"`
+	Trash          []TrashedMenu                  `json:"trash"`
+	Operations     []BoardOperation               `json:"operations"`
+	DietProfiles   map[string]DietProfile         `json:"diet_profiles"`
+}
+
+// Store keeps board history, menu templates and channel configs, persisted to the file when path is given
+type Store struct {
+	Path  string
+	mutex sync.Mutex
+	data  storeData
+}
+
+// NewStore creates store and loads the data saved in the file
+func NewStore(path string) (*Store, error) {
+	store := &Store{Path: path}
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &store.data); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if store.data.Templates == nil {
+		store.data.Templates = map[string]map[string][]string{}
+	}
+	if store.data.ChannelConfigs == nil {
+		store.data.ChannelConfigs = map[string]ChannelConfig{}
+	}
+	if store.data.DietProfiles == nil {
+		store.data.DietProfiles = map[string]DietProfile{}
+	}
+	return store, nil
+}
+
+// save writes the data to the file, the mutex should be held
+func (s *Store) save() error {
+	if s.Path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.data)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(s.Path, data)
+}
+
+// NewBoardRecord makes record of the terminated menu board
+func NewBoardRecord(teamID string, mb *MenuBoard) BoardRecord {
+	guests := map[string]string{}
+	for _, guest := range mb.Guests {
+		guests[guest.ChooserName()] = guest.SponsorUserID
+	}
+	return BoardRecord{
+		TeamID:       teamID,
+		ChannelID:    mb.ChannelID,
+		Timestamp:    mb.Timestamp,
+		Title:        mb.Title,
+		HostUserID:   mb.HostUserID,
+		TerminatedAt: time.Now(),
+		Menus:        newMenuRecords(mb),
+		Guests:       guests,
+	}
+}
+
+// NewActiveBoard makes open board of the board just posted or reopened
+func NewActiveBoard(teamID string, mb *MenuBoard) ActiveBoard {
+	return ActiveBoard{
+		TeamID:          teamID,
+		ChannelID:       mb.ChannelID,
+		Timestamp:       mb.Timestamp,
+		Title:           mb.Title,
+		HostUserID:      mb.HostUserID,
+		StartedAt:       time.Now(),
+		ThreadTimestamp: mb.ThreadTimestamp,
+		CohostUserIDs:   mb.CohostUserIDs,
+		Menus:           newMenuRecords(mb),
+	}
+}
+
+// newMenuRecords returns the menus of the board with their choosers
+func newMenuRecords(mb *MenuBoard) []MenuRecord {
+	menus := []MenuRecord{}
+	for _, menu := range mb.Menus {
+		menus = append(menus, MenuRecord{MenuName: menu.MenuName, Choosers: menu.GetChoosers()})
+	}
+	return menus
+}
+
+// NewTrashedMenu makes trashed menu of the menu about to be deleted from the board
+func NewTrashedMenu(teamID string, mb *MenuBoard, menuName string, deletedBy string) TrashedMenu {
+	menu := mb.Menus[mb.MenuNameIndexMap[menuName]]
+	choosers := []TrashedChooser{}
+	for _, statusBlock := range menu.StatusBlocks {
+		for _, element := range statusBlock.ContextElements.Elements {
+			if image, ok := element.(*slack.ImageBlockElement); ok {
+				choosers = append(choosers, TrashedChooser{Name: image.AltText, ImageURL: image.ImageURL})
+			}
+		}
+	}
+	return TrashedMenu{
+		TeamID:        teamID,
+		ChannelID:     mb.ChannelID,
+		Timestamp:     mb.Timestamp,
+		MenuName:      menuName,
+		Position:      mb.MenuNameIndexMap[menuName],
+		Emoji:         menu.EmojiText(),
+		CreatorUserID: menu.CreatorUserID,
+		Tags:          menu.Tags,
+		Choosers:      choosers,
+		DeletedBy:     deletedBy,
+		DeletedAt:     time.Now(),
+	}
+}
+
+// RecordBoard appends the terminated board to history
+func (s *Store) RecordBoard(record BoardRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data.Boards = append(s.data.Boards, record)
+	if len(s.data.Boards) > maxBoardRecords {
+		s.data.Boards = s.data.Boards[len(s.data.Boards)-maxBoardRecords:]
+	}
+	return s.save()
+}
+
+// ChannelBoards returns terminated boards of the channel, latest first
+func (s *Store) ChannelBoards(teamID string, channelID string) []BoardRecord {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	records := []BoardRecord{}
+	for i := len(s.data.Boards) - 1; i >= 0; i-- {
+		if s.data.Boards[i].TeamID == teamID && s.data.Boards[i].ChannelID == channelID {
+			records = append(records, s.data.Boards[i])
+		}
+	}
+	return records
+}
+
+// TeamBoards returns terminated boards of the team, latest first
+func (s *Store) TeamBoards(teamID string) []BoardRecord {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	records := []BoardRecord{}
+	for i := len(s.data.Boards) - 1; i >= 0; i-- {
+		if s.data.Boards[i].TeamID == teamID {
+			records = append(records, s.data.Boards[i])
+		}
+	}
+	return records
+}
+
+// AddActiveBoard keeps the board as open until it is removed
+func (s *Store) AddActiveBoard(board ActiveBoard) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data.ActiveBoards = append(s.data.ActiveBoards, board)
+	if len(s.data.ActiveBoards) > maxActiveBoards {
+		s.data.ActiveBoards = s.data.ActiveBoards[len(s.data.ActiveBoards)-maxActiveBoards:]
+	}
+	return s.save()
+}
+
+// RemoveActiveBoard forgets the open board
+func (s *Store) RemoveActiveBoard(teamID string, channelID string, timestamp string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	boards := []ActiveBoard{}
+	for _, board := range s.data.ActiveBoards {
+		if board.TeamID != teamID || board.ChannelID != channelID || board.Timestamp != timestamp {
+			boards = append(boards, board)
+		}
+	}
+	if len(boards) == len(s.data.ActiveBoards) {
+		return nil
+	}
+	s.data.ActiveBoards = boards
+	return s.save()
+}
+
+// ActiveBoards returns open boards of the team, latest first
+func (s *Store) ActiveBoards(teamID string) []ActiveBoard {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	boards := []ActiveBoard{}
+	for i := len(s.data.ActiveBoards) - 1; i >= 0; i-- {
+		if s.data.ActiveBoards[i].TeamID == teamID {
+			boards = append(boards, s.data.ActiveBoards[i])
+		}
+	}
+	return boards
+}
+
+// HasActiveThread returns whether the thread has an open board
+func (s *Store) HasActiveThread(teamID string, channelID string, threadTimestamp string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, board := range s.data.ActiveBoards {
+		if board.TeamID == teamID && board.ChannelID == channelID && (board.ThreadTimestamp == threadTimestamp || board.Timestamp == threadTimestamp) {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveBoardsWithDeadline returns open boards of all teams which have deadlines
+func (s *Store) ActiveBoardsWithDeadline() []ActiveBoard {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	boards := []ActiveBoard{}
+	for _, board := range s.data.ActiveBoards {
+		if !board.Deadline.IsZero() {
+			boards = append(boards, board)
+		}
+	}
+	return boards
+}
+
+// SetActiveBoardDeadline keeps the deadline of the open board
+func (s *Store) SetActiveBoardDeadline(teamID string, channelID string, timestamp string, deadline time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, board := range s.data.ActiveBoards {
+		if board.TeamID == teamID && board.ChannelID == channelID && board.Timestamp == timestamp {
+			s.data.ActiveBoards[i].Deadline = deadline
+			return s.save()
+		}
+	}
+	return nil
+}
+
+// SetActiveBoardChoices keeps the co-hosts and the choices of the open board
+func (s *Store) SetActiveBoardChoices(teamID string, channelID string, timestamp string, cohostUserIDs []string, menus []MenuRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, board := range s.data.ActiveBoards {
+		if board.TeamID == teamID && board.ChannelID == channelID && board.Timestamp == timestamp {
+			s.data.ActiveBoards[i].CohostUserIDs = cohostUserIDs
+			s.data.ActiveBoards[i].Menus = menus
+			return s.save()
+		}
+	}
+	return nil
+}
+
+// TrashMenu keeps the deleted menu until trashTTL passes, replacing the one of the same name on the board
+func (s *Store) TrashMenu(menu TrashedMenu) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	trash := []TrashedMenu{}
+	for _, trashed := range s.data.Trash {
+		if time.Since(trashed.DeletedAt) > trashTTL || isSameTrashedMenu(trashed, menu.TeamID, menu.ChannelID, menu.Timestamp, menu.MenuName) {
+			continue
+		}
+		trash = append(trash, trashed)
+	}
+	s.data.Trash = append(trash, menu)
+	return s.save()
+}
+
+// TrashedMenus returns deleted menus of the board which can still be restored, latest first
+func (s *Store) TrashedMenus(teamID string, channelID string, timestamp string) []TrashedMenu {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	menus := []TrashedMenu{}
+	for i := len(s.data.Trash) - 1; i >= 0; i-- {
+		trashed := s.data.Trash[i]
+		if time.Since(trashed.DeletedAt) <= trashTTL && trashed.TeamID == teamID && trashed.ChannelID == channelID && trashed.Timestamp == timestamp {
+			menus = append(menus, trashed)
+		}
+	}
+	return menus
+}
+
+// TakeTrashedMenu removes the deleted menu from the trash and returns it, false if it is expired or already restored
+func (s *Store) TakeTrashedMenu(teamID string, channelID string, timestamp string, menuName string) (TrashedMenu, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, trashed := range s.data.Trash {
+		if isSameTrashedMenu(trashed, teamID, channelID, timestamp, menuName) {
+			s.data.Trash = append(s.data.Trash[:i], s.data.Trash[i+1:]...)
+			if time.Since(trashed.DeletedAt) > trashTTL {
+				return TrashedMenu{}, false, s.save()
+			}
+			return trashed, true, s.save()
+		}
+	}
+	return TrashedMenu{}, false, nil
+}
+
+func isSameTrashedMenu(trashed TrashedMenu, teamID string, channelID string, timestamp string, menuName string) bool {
+	return trashed.TeamID == teamID && trashed.ChannelID == channelID && trashed.Timestamp == timestamp && trashed.MenuName == menuName
+}
+
+// RecordOperation appends the change applied to the board
+func (s *Store) RecordOperation(operation BoardOperation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data.Operations = append(s.data.Operations, operation)
+	if i := s.operationIndex(operation.TeamID, operation.ChannelID, operation.Timestamp, maxOperationsPerBoard); i >= 0 {
+		s.data.Operations = append(s.data.Operations[:i], s.data.Operations[i+1:]...)
+	}
+	if len(s.data.Operations) > maxOperations {
+		s.data.Operations = s.data.Operations[len(s.data.Operations)-maxOperations:]
+	}
+	return s.save()
+}
+
+// LastOperation returns the last change of the board which is not undone yet
+func (s *Store) LastOperation(teamID string, channelID string, timestamp string) (BoardOperation, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if i := s.lastOperationIndex(teamID, channelID, timestamp); i >= 0 {
+		return s.data.Operations[i], true
+	}
+	return BoardOperation{}, false
+}
+
+// PopOperation forgets the last change of the board after it is undone
+func (s *Store) PopOperation(teamID string, channelID string, timestamp string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.lastOperationIndex(teamID, channelID, timestamp)
+	if i < 0 {
+		return nil
+	}
+	s.data.Operations = append(s.data.Operations[:i], s.data.Operations[i+1:]...)
+	return s.save()
+}
+
+func (s *Store) lastOperationIndex(teamID string, channelID string, timestamp string) int {
+	return s.operationIndex(teamID, channelID, timestamp, 0)
+}
+
+// operationIndex returns the index of the operation of the board which has skip newer operations of the board, or -1
+func (s *Store) operationIndex(teamID string, channelID string, timestamp string, skip int) int {
+	for i := len(s.data.Operations) - 1; i >= 0; i-- {
+		operation := s.data.Operations[i]
+		if operation.TeamID == teamID && operation.ChannelID == channelID && operation.Timestamp == timestamp {
+			if skip == 0 {
+				return i
+			}
+			skip--
+		}
+	}
+	return -1
+}
+
+// RemoveBoardRecord forgets the terminated board, when it is opened again
+func (s *Store) RemoveBoardRecord(teamID string, channelID string, timestamp string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := len(s.data.Boards) - 1; i >= 0; i-- {
+		record := s.data.Boards[i]
+		if record.TeamID == teamID && record.ChannelID == channelID && record.Timestamp == timestamp {
+			s.data.Boards = append(s.data.Boards[:i], s.data.Boards[i+1:]...)
+			return s.save()
+		}
+	}
+	return nil
+}
+
+// Templates returns menu templates of the team sorted by name
+func (s *Store) Templates(teamID string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	names := []string{}
+	for name := range s.data.Templates[teamID] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Template returns menus of the template
+func (s *Store) Template(teamID string, name string) ([]string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	menus, ok := s.data.Templates[teamID][name]
+	return menus, ok
+}
+
+// SaveTemplate saves menus as the template
+func (s *Store) SaveTemplate(teamID string, name string, menus []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.data.Templates[teamID] == nil {
+		s.data.Templates[teamID] = map[string][]string{}
+	}
+	s.data.Templates[teamID][name] = menus
+	return s.save()
+}
+
+// DeleteTemplate deletes the template and returns whether it existed
+func (s *Store) DeleteTemplate(teamID string, name string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.Templates[teamID][name]; !ok {
+		return false, nil
+	}
+	delete(s.data.Templates[teamID], name)
+	return true, s.save()
+}
+
+// ChannelConfig returns the config of the channel
+func (s *Store) ChannelConfig(teamID string, channelID string) ChannelConfig {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.data.ChannelConfigs[teamID+"/"+channelID]
+}
+
+// SaveChannelConfig saves the config of the channel
+func (s *Store) SaveChannelConfig(teamID string, channelID string, config ChannelConfig) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data.ChannelConfigs[teamID+"/"+channelID] = config
+	return s.save()
+}
+
+// DietProfile returns the dietary restrictions of the user
+func (s *Store) DietProfile(teamID string, userID string) DietProfile {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.data.DietProfiles[teamID+"/"+userID]
+}
+
+// SaveDietProfile saves the dietary restrictions of the user, forgetting the user when there is none
+func (s *Store) SaveDietProfile(teamID string, userID string, profile DietProfile) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(profile.Restrictions) == 0 {
+		delete(s.data.DietProfiles, teamID+"/"+userID)
+	} else {
+		s.data.DietProfiles[teamID+"/"+userID] = profile
+	}
+	return s.save()
+}
+
+// DietRestrictionsByUser returns the dietary restrictions of the team keyed by user id
+func (s *Store) DietRestrictionsByUser(teamID string) map[string][]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	restrictions := map[string][]string{}
+	for key, profile := range s.data.DietProfiles {
+		if strings.HasPrefix(key, teamID+"/") {
+			restrictions[strings.TrimPrefix(key, teamID+"/")] = profile.Restrictions
+		}
+	}
+	return restrictions
+}
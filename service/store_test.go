@@ -0,0 +1,70 @@
+package service
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "store.json")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveTemplate("T1", "중국집", []string{"짜장면", "짬뽕"}); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if menus, _ := reloaded.Template("T1", "중국집"); len(menus) != 2 {
+		t.Errorf("reloaded template = %v, want 2 menus", menus)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("store left %d files, want only the store file", len(files))
+	}
+}
+
+func TestStoreRecordOperation(t *testing.T) {
+	store, err := NewStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxOperationsPerBoard+10; i++ {
+		if err := store.RecordOperation(BoardOperation{TeamID: "T1", ChannelID: "C1", Timestamp: "1", Kind: OperationAdd, MenuNames: []string{string(rune('a' + i%26))}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.RecordOperation(BoardOperation{TeamID: "T1", ChannelID: "C1", Timestamp: "2", Kind: OperationAdd}); err != nil {
+		t.Fatal(err)
+	}
+
+	board := 0
+	for i := 0; ; i++ {
+		operation, ok := store.LastOperation("T1", "C1", "1")
+		if !ok {
+			break
+		}
+		if i == 0 && operation.MenuNames[0] != string(rune('a'+(maxOperationsPerBoard+9)%26)) {
+			t.Errorf("last operation = %v, want the newest one", operation.MenuNames)
+		}
+		board++
+		if err := store.PopOperation("T1", "C1", "1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if board != maxOperationsPerBoard {
+		t.Errorf("kept %d operations of the board, want %d", board, maxOperationsPerBoard)
+	}
+	if _, ok := store.LastOperation("T1", "C1", "2"); !ok {
+		t.Error("operation of another board was dropped")
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// Installation is the bot token of a workspace which installed the app
+type Installation struct {
+	TeamID    string `json:"team_id"`
+	TeamName  string `json:"team_name"`
+	BotToken  string `json:"bot_token"`
+	BotUserID string `json:"bot_user_id"`
+}
+
+// TokenStore keeps installations keyed by team id, persisted to the file when path is given
+type TokenStore struct {
+	Path          string
+	mutex         sync.Mutex
+	installations map[string]Installation
+}
+
+// NewTokenStore creates token store and loads installations saved in the file
+func NewTokenStore(path string) (*TokenStore, error) {
+	store := &TokenStore{
+		Path:          path,
+		installations: map[string]Installation{},
+	}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.installations); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Get returns installation of the team
+func (ts *TokenStore) Get(teamID string) (Installation, bool) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	installation, ok := ts.installations[teamID]
+	return installation, ok
+}
+
+// Save stores installation of the team, replacing the previous one
+func (ts *TokenStore) Save(installation Installation) error {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	ts.installations[installation.TeamID] = installation
+	if ts.Path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(ts.installations, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(ts.Path, data)
+}
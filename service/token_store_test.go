@@ -0,0 +1,55 @@
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTokenStoreSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tokens.json")
+	store, err := NewTokenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, installation := range []Installation{
+		{TeamID: "T1", TeamName: "첫 팀", BotToken: "xoxb-1", BotUserID: "UBOT1"},
+		{TeamID: "T2", TeamName: "둘째 팀", BotToken: "xoxb-2", BotUserID: "UBOT2"},
+		{TeamID: "T1", TeamName: "첫 팀", BotToken: "xoxb-1-again", BotUserID: "UBOT1"},
+	} {
+		if err := store.Save(installation); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reloaded, err := NewTokenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if installation, ok := reloaded.Get("T1"); !ok || installation.BotToken != "xoxb-1-again" {
+		t.Errorf("installation of T1 = %+v, %v, want the latest token", installation, ok)
+	}
+	if installation, ok := reloaded.Get("T2"); !ok || installation.BotUserID != "UBOT2" {
+		t.Errorf("installation of T2 = %+v, %v, want the saved one", installation, ok)
+	}
+	if _, ok := reloaded.Get("T3"); ok {
+		t.Error("installation of T3 is found, want none")
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("token store left %d files, want only the token file", len(files))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("token file mode = %v, want readable only by the owner", perm)
+	}
+}
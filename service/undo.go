@@ -0,0 +1,232 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// RecordToggle records that the choices of the users on the menu are toggled by the user
+func RecordToggle(handler *Handler, menuBoard *MenuBoard, userID string, menuName string, profiles []*slack.UserProfile) error {
+	choosers := []TrashedChooser{}
+	for _, profile := range profiles {
+		choosers = append(choosers, TrashedChooser{Name: profile.RealName, ImageURL: profile.Image32})
+	}
+	operation := newBoardOperation(handler, menuBoard, OperationToggle, userID)
+	operation.MenuNames = []string{menuName}
+	operation.Choosers = choosers
+	return handler.Store.RecordOperation(operation)
+}
+
+// RecordAdd records that the menus are added by the user
+func RecordAdd(handler *Handler, menuBoard *MenuBoard, userID string, menuNames []string) error {
+	operation := newBoardOperation(handler, menuBoard, OperationAdd, userID)
+	operation.MenuNames = menuNames
+	return handler.Store.RecordOperation(operation)
+}
+
+// RecordDelete records that the menu is deleted by the user
+func RecordDelete(handler *Handler, menuBoard *MenuBoard, userID string, trashed TrashedMenu) error {
+	operation := newBoardOperation(handler, menuBoard, OperationDelete, userID)
+	operation.MenuNames = []string{trashed.MenuName}
+	operation.Trashed = &trashed
+	return handler.Store.RecordOperation(operation)
+}
+
+// RecordTerminate records that the board is terminated by the user, or by the bot when user is empty
+func RecordTerminate(handler *Handler, menuBoard *MenuBoard, userID string) error {
+	return handler.Store.RecordOperation(newBoardOperation(handler, menuBoard, OperationTerminate, userID))
+}
+
+func newBoardOperation(handler *Handler, menuBoard *MenuBoard, kind string, userID string) BoardOperation {
+	return BoardOperation{
+		TeamID:    handler.TeamID,
+		ChannelID: menuBoard.ChannelID,
+		Timestamp: menuBoard.Timestamp,
+		Kind:      kind,
+		UserID:    userID,
+		At:        time.Now(),
+	}
+}
+
+// UndoLastOperation reverts the last change of the board and returns the message for the user, the caller should hold messageUpdateMutex
+func UndoLastOperation(handler *Handler, menuBoard *MenuBoard, userID string) (string, error) {
+	operation, ok := handler.Store.LastOperation(handler.TeamID, menuBoard.ChannelID, menuBoard.Timestamp)
+	if !ok {
+		return "되돌릴 변경이 없다옹", nil
+	}
+	if operation.UserID != userID {
+		canManage, err := CanManageBoard(handler, menuBoard, userID)
+		if err != nil {
+			return "", err
+		}
+		if !canManage && operation.UserID == "" {
+			return "마지막 변경은 메뉴판을 연 사람이나 같이 여는 사람만 되돌릴 수 있다옹", nil
+		}
+		if !canManage {
+			return fmt.Sprintf("마지막 변경은 <@%s>님이 했으니 그 사람이나 메뉴판을 연 사람만 되돌릴 수 있다옹", operation.UserID), nil
+		}
+	}
+	if operation.Kind != OperationTerminate && menuBoard.IsTerminated() {
+		return "이미 마감된 메뉴판이다옹", nil
+	}
+
+	original, err := menuBoard.Clone()
+	if err != nil {
+		return "", err
+	}
+	description := ""
+	trash := []TrashedMenu{}
+	switch operation.Kind {
+	case OperationToggle:
+		menuName := operation.MenuNames[0]
+		if _, ok := menuBoard.MenuNameIndexMap[menuName]; ok {
+			for _, chooser := range operation.Choosers {
+				menuBoard.ToggleMenuByUser(&slack.UserProfile{RealName: chooser.Name, Image32: chooser.ImageURL}, menuName)
+			}
+		}
+		description = fmt.Sprintf("*%s* 선택/취소", menuName)
+
+	case OperationAdd:
+		// Menus added by mistake are kept in trash too, in case they are chosen already
+		for _, menuName := range operation.MenuNames {
+			if _, ok := menuBoard.MenuNameIndexMap[menuName]; !ok {
+				continue
+			}
+			trash = append(trash, NewTrashedMenu(handler.TeamID, menuBoard, menuName, userID))
+			menuBoard.DeleteMenu(menuName)
+		}
+		description = fmt.Sprintf("*%s* 추가", strings.Join(operation.MenuNames, ", "))
+
+	case OperationDelete:
+		if _, ok := menuBoard.MenuNameIndexMap[operation.Trashed.MenuName]; ok {
+			return fmt.Sprintf("*%s*는 이미 메뉴판에 다시 있다옹", operation.Trashed.MenuName), nil
+		}
+		menuBoard.RestoreMenu(*operation.Trashed)
+		description = fmt.Sprintf("*%s* 삭제", operation.Trashed.MenuName)
+
+	case OperationTerminate:
+		menuBoard.Reopen()
+		description = "마감"
+	}
+
+	// The operation stays in the log until all pages are updated, and pages written before a failure are put back
+	if err := SaveOpenBoard(handler, menuBoard); err != nil {
+		original.ContinuationTimestamps = menuBoard.ContinuationTimestamps
+		if rollbackErr := SaveMenuBoard(handler.Client, original); rollbackErr != nil {
+			handler.Logger.Printf("[ERROR] Failed to put back the board after failed undo: %v\n", rollbackErr)
+		}
+		return "", err
+	}
+	if err := handler.Store.PopOperation(handler.TeamID, menuBoard.ChannelID, menuBoard.Timestamp); err != nil {
+		return "", err
+	}
+
+	for _, trashed := range trash {
+		if err := handler.Store.TrashMenu(trashed); err != nil {
+			return "", err
+		}
+	}
+	if operation.Kind == OperationDelete {
+		if _, _, err := handler.Store.TakeTrashedMenu(handler.TeamID, menuBoard.ChannelID, menuBoard.Timestamp, operation.Trashed.MenuName); err != nil {
+			return "", err
+		}
+	}
+	if operation.Kind == OperationTerminate {
+		if err := handler.Store.RemoveBoardRecord(handler.TeamID, menuBoard.ChannelID, menuBoard.Timestamp); err != nil {
+			return "", err
+		}
+		if err := AddActiveBoard(handler, menuBoard); err != nil {
+			return "", err
+		}
+		if !menuBoard.Deadline.IsZero() {
+			if err := ScheduleDeadline(handler, menuBoard); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := auditUndo(handler, menuBoard, userID, operation); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("↩️ 되돌렸다옹: %s", description), nil
+}
+
+// auditUndo appends the changes made by undoing the operation to the audit log
+func auditUndo(handler *Handler, menuBoard *MenuBoard, userID string, operation BoardOperation) error {
+	switch operation.Kind {
+	case OperationToggle:
+		choosers := []string{}
+		for _, chooser := range operation.Choosers {
+			choosers = append(choosers, chooser.Name)
+		}
+		return AuditToggles(handler, menuBoard, userID, operation.MenuNames[0], choosers, "undo")
+	case OperationAdd:
+		for _, menuName := range operation.MenuNames {
+			if err := AuditBoard(handler, menuBoard, userID, AuditDelete, menuName, "undo"); err != nil {
+				return err
+			}
+		}
+	case OperationDelete:
+		return AuditBoard(handler, menuBoard, userID, AuditRestore, operation.Trashed.MenuName, "undo")
+	case OperationTerminate:
+		return AuditBoard(handler, menuBoard, userID, AuditReopen, "", "undo")
+	}
+	return nil
+}
+
+// UndoBoardChange handles when user clicks undo button of the board
+func UndoBoardChange(handler *Handler, payload *slack.InteractionCallback) error {
+	messageUpdateMutex.Lock()
+	defer messageUpdateMutex.Unlock()
+
+	menuBoard, err := LoadMenuBoard(handler.Client, payload.Channel.ID, payload.Message.Timestamp)
+	if err != nil {
+		return err
+	}
+	text, err := UndoLastOperation(handler, menuBoard, payload.User.ID)
+	if err != nil {
+		return err
+	}
+	return ReplyEphemeral(handler, menuBoard, payload.User.ID, text)
+}
+
+// UndoCommand reverts the last change among the boards of the thread, which can be a terminated one
+func UndoCommand(ctx *CommandContext, args string) error {
+	if ctx.ThreadTimestamp == "" {
+		return ctx.Reply("메뉴판 스레드에서 `@Waiter Bot undo` 처럼 불러달라옹", true)
+	}
+	handler := ctx.Handler
+	messages, _, _, err := handler.Client.GetConversationReplies(&slack.GetConversationRepliesParameters{ChannelID: ctx.ChannelID, Timestamp: ctx.ThreadTimestamp})
+	if err != nil {
+		return err
+	}
+
+	boardTimestamp := ""
+	var lastAt time.Time
+	for _, message := range messages {
+		if message.User != handler.BotUserID || len(message.Blocks.BlockSet) == 0 || message.Blocks.BlockSet[0].BlockType() != slack.MBTHeader {
+			continue
+		}
+		if operation, ok := handler.Store.LastOperation(handler.TeamID, ctx.ChannelID, message.Timestamp); ok && !operation.At.Before(lastAt) {
+			boardTimestamp, lastAt = message.Timestamp, operation.At
+		}
+	}
+	if boardTimestamp == "" {
+		return ctx.Reply("되돌릴 변경이 없다옹", true)
+	}
+
+	messageUpdateMutex.Lock()
+	defer messageUpdateMutex.Unlock()
+
+	menuBoard, err := LoadMenuBoard(handler.Client, ctx.ChannelID, boardTimestamp)
+	if err != nil {
+		return err
+	}
+	text, err := UndoLastOperation(handler, menuBoard, ctx.UserID)
+	if err != nil {
+		return err
+	}
+	return ctx.Reply(text, true)
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slack-waiter-bot/fakeslack"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// failingSlack fails to update the message of the timestamp, like slack rate limiting in the middle of a save
+type failingSlack struct {
+	*fakeslack.Slack
+	failTimestamp string
+}
+
+func (s *failingSlack) UpdateMessage(channelID string, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	if timestamp == s.failTimestamp {
+		return "", "", "", errors.New("ratelimited")
+	}
+	return s.Slack.UpdateMessage(channelID, timestamp, options...)
+}
+
+func TestUndoPagedBoard(t *testing.T) {
+	handler, fake := newTestHandler(t)
+	fake.AddUser("U1", "호스트")
+
+	// Undoing the add of the first menu moves menus of the continuation page up into the board message
+	menuBoard := NewMenuBoard("점심", "U1")
+	if err := PostNewBoard(handler, "C1", "", menuBoard); err != nil {
+		t.Fatal(err)
+	}
+	wantMenus := []string{}
+	for i := 0; i < 60; i++ {
+		menuName := fmt.Sprintf("메뉴%d", i)
+		menuBoard.AddMenu(menuName, "", "U1")
+		wantMenus = append(wantMenus, menuName)
+	}
+	if err := SaveMenuBoard(fake, menuBoard); err != nil {
+		t.Fatal(err)
+	}
+	if len(menuBoard.ContinuationTimestamps) == 0 {
+		t.Fatal("board has no continuation page")
+	}
+	if err := RecordAdd(handler, menuBoard, "U1", []string{"메뉴0"}); err != nil {
+		t.Fatal(err)
+	}
+	menuNames := func() []string {
+		loaded, err := LoadMenuBoard(fake, "C1", menuBoard.Timestamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, menu := range loaded.Menus {
+			names = append(names, menu.MenuName)
+		}
+		return names
+	}
+	undo := func() error {
+		messageUpdateMutex.Lock()
+		defer messageUpdateMutex.Unlock()
+
+		loaded, err := LoadMenuBoard(handler.Client, "C1", menuBoard.Timestamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = UndoLastOperation(handler, loaded, "U1")
+		return err
+	}
+
+	// Failing on the continuation page puts the board message back and keeps the operation to undo again
+	handler.Client = &failingSlack{Slack: fake, failTimestamp: menuBoard.ContinuationTimestamps[0]}
+	if err := undo(); err == nil {
+		t.Fatal("undo succeeded, want the error of the continuation page")
+	}
+	if got := menuNames(); !reflect.DeepEqual(got, wantMenus) {
+		t.Errorf("menus = %v, want the board put back", got)
+	}
+	if operation, ok := handler.Store.LastOperation("T1", "C1", menuBoard.Timestamp); !ok || operation.Kind != OperationAdd {
+		t.Errorf("last operation = %+v, %v, want the add kept", operation, ok)
+	}
+	if trash := handler.Store.TrashedMenus("T1", "C1", menuBoard.Timestamp); len(trash) != 0 {
+		t.Errorf("trash = %+v, want nothing trashed by failed undo", trash)
+	}
+
+	// Undoing again applies to all pages and takes the operation off
+	handler.Client = fake
+	if err := undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := menuNames(); !reflect.DeepEqual(got, wantMenus[1:]) {
+		t.Errorf("menus = %v, want the added menu undone", got)
+	}
+	if operation, ok := handler.Store.LastOperation("T1", "C1", menuBoard.Timestamp); ok {
+		t.Errorf("last operation = %+v, want the add taken off", operation)
+	}
+	if trash := handler.Store.TrashedMenus("T1", "C1", menuBoard.Timestamp); len(trash) != 1 || trash[0].MenuName != "메뉴0" {
+		t.Errorf("trash = %+v, want the undone menu", trash)
+	}
+}
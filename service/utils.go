@@ -3,7 +3,10 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/slack-go/slack"
@@ -22,27 +25,35 @@ func URLVerification(w http.ResponseWriter, body []byte) {
 	w.Write([]byte(r.Challenge))
 }
 
-// AuthorizeSlack is the function to authorize with bot token and return client, bot user id
-func AuthorizeSlack(slackBotToken string) (*slack.Client, string, error) {
-	client := slack.New(slackBotToken)
+// RespondJSON writes the payload which slack expects as synchronous response
+func RespondJSON(w http.ResponseWriter, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payload)
+}
+
+// AuthorizeSlack is the function to authorize with bot token and return client, bot user id, team id
+func AuthorizeSlack(slackBotToken string, options ...slack.Option) (*slack.Client, string, string, error) {
+	client := slack.New(slackBotToken, options...)
 
 	bot, err := client.AuthTest()
 	if err != nil {
-		return nil, "", err
+		return nil, "", "", err
 	}
-	botUserID := bot.UserID
-	return client, botUserID, nil
+	return client, bot.UserID, bot.TeamID, nil
 }
 
 // GetMessageFromTimeStamp retrieve slack message using channel and timestamp
-func GetMessageFromTimeStamp(client *slack.Client, channelID string, timestamp string) *slack.Message {
-	messages, _, _, _ := client.GetConversationReplies(&slack.GetConversationRepliesParameters{ChannelID: channelID, Timestamp: timestamp})
+func GetMessageFromTimeStamp(client SlackAPI, channelID string, timestamp string) (*slack.Message, error) {
+	messages, _, _, err := client.GetConversationReplies(&slack.GetConversationRepliesParameters{ChannelID: channelID, Timestamp: timestamp})
+	if err != nil {
+		return nil, err
+	}
 	for _, message := range messages {
 		if message.Timestamp == timestamp {
-			return &message
+			return &message, nil
 		}
 	}
-	return nil
+	return nil, ErrMenuBoardNotFound
 }
 
 // WriteCallbackMetadata returns private metadata for add menu view
@@ -50,10 +61,71 @@ func WriteCallbackMetadata(channelID string, timestamp string) string {
 	return fmt.Sprintf("%s\t%s", channelID, timestamp)
 }
 
+// WriteMenuMetadata returns private metadata or button value pointing the menu of the board
+func WriteMenuMetadata(channelID string, timestamp string, menuName string) string {
+	return fmt.Sprintf("%s\t%s\t%s", channelID, timestamp, menuName)
+}
+
+// ParseMenuMetadata returns channel, board timestamp and menu name written by WriteMenuMetadata
+func ParseMenuMetadata(metadata string) (string, string, string) {
+	menuInfo := strings.SplitN(metadata, "\t", 3)
+	if len(menuInfo) < 3 {
+		return "", "", ""
+	}
+	return menuInfo[0], menuInfo[1], menuInfo[2]
+}
+
+// WriteOrderMetadata returns private metadata of order for other view confirming the mode, the users and the guests on the menu
+func WriteOrderMetadata(channelID string, timestamp string, mode string, userIDs []string, guestNames []string, menuName string) string {
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s", channelID, timestamp, mode, strings.Join(userIDs, ","), strings.Join(guestNames, ","), menuName)
+}
+
+// ParseOrderMetadata returns channel, board timestamp, mode, users, guests and menu name written by WriteOrderMetadata
+func ParseOrderMetadata(metadata string) (string, string, string, []string, []string, string) {
+	orderInfo := strings.SplitN(metadata, "\t", 6)
+	if len(orderInfo) < 6 {
+		return "", "", "", nil, nil, ""
+	}
+	return orderInfo[0], orderInfo[1], orderInfo[2], splitList(orderInfo[3]), splitList(orderInfo[4]), orderInfo[5]
+}
+
+// splitList splits comma separated values, which is empty for empty text
+func splitList(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(text, ",")
+}
+
 // ParseCallbackMetadata returns parsed informations of add menu view
 func ParseCallbackMetadata(privateMetadata string) (string, string) {
 	callbackInfo := strings.Split(privateMetadata, "\t")
+	if len(callbackInfo) < 2 {
+		return "", ""
+	}
 	channel := callbackInfo[0]
 	originalPostTimeStamp := callbackInfo[1]
 	return channel, originalPostTimeStamp
 }
+
+// writeFileAtomically writes next to the file readable only by the owner and renames over it,
+// so that a crash in the middle never leaves a truncated file
+func writeFileAtomically(path string, data []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
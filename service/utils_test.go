@@ -0,0 +1,22 @@
+package service
+
+import "testing"
+
+func TestParseCallbackMetadata(t *testing.T) {
+	tests := []struct {
+		metadata      string
+		wantChannel   string
+		wantTimestamp string
+	}{
+		{metadata: WriteCallbackMetadata("C1", "1600000000.000001"), wantChannel: "C1", wantTimestamp: "1600000000.000001"},
+		{metadata: WriteMenuMetadata("C1", "1600000000.000001", "짜장면"), wantChannel: "C1", wantTimestamp: "1600000000.000001"},
+		{metadata: "C1"},
+		{metadata: ""},
+	}
+	for _, test := range tests {
+		channel, timestamp := ParseCallbackMetadata(test.metadata)
+		if channel != test.wantChannel || timestamp != test.wantTimestamp {
+			t.Errorf("ParseCallbackMetadata(%q) = %q, %q, want %q, %q", test.metadata, channel, timestamp, test.wantChannel, test.wantTimestamp)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"fmt"
+	"slack-waiter-bot/ids"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+// maxMenuNameLength is the limit of slack option text which menu names are shown in
+const maxMenuNameLength = 75
+
+// maxBlockIDLength is the limit of slack block ids in bytes, which keep menu names with their creators and tags
+const maxBlockIDLength = 255
+
+// ValidateMenuName returns error message for invalid menu name of the creator or empty string when it is valid
+func (mb *MenuBoard) ValidateMenuName(menuName string, creatorUserID string) string {
+	menuName = strings.TrimSpace(menuName)
+	if menuName == "" {
+		return "메뉴 이름을 적어달라옹"
+	}
+	if strings.Contains(menuName, "/") {
+		return "메뉴 이름에 '/'는 쓸 수 없다옹"
+	}
+	if utf8.RuneCountInString(menuName) > maxMenuNameLength || !fitsMenuBlockIDs(menuName, creatorUserID, nil) {
+		return "메뉴 이름이 너무 길다옹"
+	}
+	if _, ok := mb.MenuNameIndexMap[menuName]; ok {
+		return "이미 있는 메뉴다옹"
+	}
+	return ""
+}
+
+// fitsMenuBlockIDs returns whether the block ids keeping the menu fit in slack, counted in bytes as multibyte names take more
+func fitsMenuBlockIDs(menuName string, creatorUserID string, tags []string) bool {
+	// Status blocks of the menu are numbered after its name, where two digits hold hundreds of choosers
+	statusBlockID := fmt.Sprintf("%s/%s/%d", ids.MenuSelectContextBlock, menuName, 99)
+	return len(menuBlockID(menuName, creatorUserID, tags)) <= maxBlockIDLength && len(statusBlockID) <= maxBlockIDLength
+}
+
+// ValidateStartBoard validates start board view and returns errors keyed by block id
+func ValidateStartBoard(handler *Handler, payload *slack.InteractionCallback) map[string]string {
+	if deadline, ok := StartBoardDeadline(handler, payload); ok && !deadline.After(time.Now()) {
+		return map[string]string{ids.StartBoardDeadlineBlock: "이미 지난 시간이다옹"}
+	}
+	menuBoard := NewMenuBoard("", payload.User.ID)
+	for _, menuName := range StartBoardMenuNames(payload) {
+		if errorMessage := menuBoard.ValidateMenuName(menuName, payload.User.ID); errorMessage != "" {
+			return map[string]string{ids.StartBoardMenusBlock: menuName + ": " + errorMessage}
+		}
+		menuBoard.AddMenu(menuName, "", payload.User.ID)
+	}
+	return nil
+}
+
+// ValidateMenuAdd validates menu add view and returns errors keyed by block id
+func ValidateMenuAdd(handler *Handler, payload *slack.InteractionCallback) map[string]string {
+	channel, originalPostTimeStamp := ParseCallbackMetadata(payload.View.PrivateMetadata)
+	menuName := payload.View.State.Values[ids.SubmitMenuInputBlock][ids.SubmitMenuInput].Value
+
+	menuBoard, err := LoadMenuBoard(handler.Client, channel, originalPostTimeStamp)
+	if err != nil {
+		return map[string]string{ids.SubmitMenuInputBlock: "메뉴판을 찾을 수 없다옹"}
+	}
+	if errorMessage := menuBoard.ValidateMenuAdd(menuName, payload.User.ID, MenuAddTags(payload)); errorMessage != "" {
+		return map[string]string{ids.SubmitMenuInputBlock: errorMessage}
+	}
+	return nil
+}
+
+// ValidateMenuAdd returns error message when the menu with the tags can not be added to the board or empty string when it can
+func (mb *MenuBoard) ValidateMenuAdd(menuName string, creatorUserID string, tags []string) string {
+	if mb.IsTerminated() {
+		return "이미 마감된 메뉴판이다옹"
+	}
+	if errorMessage := mb.ValidateMenuName(menuName, creatorUserID); errorMessage != "" {
+		return errorMessage
+	}
+	if !fitsMenuBlockIDs(strings.TrimSpace(menuName), creatorUserID, tags) {
+		return "메뉴 이름과 재료를 합쳐서 너무 길다옹"
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestValidateMenuAdd(t *testing.T) {
+	newBoard := func() *MenuBoard {
+		menuBoard := NewMenuBoard("점심", "U1")
+		menuBoard.AddMenu("짜장면", "", "U1")
+		return menuBoard
+	}
+	closedBoard := newBoard()
+	closedBoard.TailBlocks = []slack.Block{slack.NewDividerBlock()}
+
+	tests := []struct {
+		name      string
+		menuBoard *MenuBoard
+		menuName  string
+		tags      []string
+		wantError bool
+	}{
+		{name: "new menu", menuBoard: newBoard(), menuName: "짬뽕"},
+		{name: "blank menu", menuBoard: newBoard(), menuName: "   ", wantError: true},
+		{name: "menu with slash", menuBoard: newBoard(), menuName: "짜장/짬뽕", wantError: true},
+		{name: "too long menu", menuBoard: newBoard(), menuName: strings.Repeat("짜", maxMenuNameLength+1), wantError: true},
+		{name: "duplicate menu", menuBoard: newBoard(), menuName: "짜장면", wantError: true},
+		{name: "duplicate menu with spaces", menuBoard: newBoard(), menuName: " 짜장면 ", wantError: true},
+		{name: "closed board", menuBoard: closedBoard, menuName: "짬뽕", wantError: true},
+		{name: "long multibyte menu", menuBoard: newBoard(), menuName: strings.Repeat("짜", maxMenuNameLength)},
+		{name: "long multibyte menu over block id bytes", menuBoard: newBoard(), menuName: strings.Repeat("🍜", maxMenuNameLength-5), wantError: true},
+		{name: "menu with tags", menuBoard: newBoard(), menuName: "탕수육", tags: menuTags},
+		{name: "long multibyte menu with tags over block id bytes", menuBoard: newBoard(), menuName: strings.Repeat("짜", maxMenuNameLength), tags: []string{MenuTagPork, MenuTagSeafood, MenuTagGluten}, wantError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if errorMessage := test.menuBoard.ValidateMenuAdd(test.menuName, "U1", test.tags); (errorMessage != "") != test.wantError {
+				t.Errorf("ValidateMenuAdd(%q, %v) = %q, want error %v", test.menuName, test.tags, errorMessage, test.wantError)
+			}
+			if test.wantError {
+				return
+			}
+			// Valid menus are kept in block ids slack accepts
+			test.menuBoard.AddMenu(test.menuName, "", "U1")
+			test.menuBoard.SetMenuTags(test.menuName, test.tags)
+			for _, block := range test.menuBoard.ToBlocks() {
+				var encoded struct {
+					BlockID string `json:"block_id"`
+				}
+				data, _ := json.Marshal(block)
+				if err := json.Unmarshal(data, &encoded); err != nil {
+					t.Fatal(err)
+				}
+				if len(encoded.BlockID) > maxBlockIDLength {
+					t.Errorf("block id %q is %d bytes, want at most %d", encoded.BlockID, len(encoded.BlockID), maxBlockIDLength)
+				}
+			}
+		})
+	}
+}
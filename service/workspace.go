@@ -0,0 +1,106 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrWorkspaceNotInstalled is returned for a team which has not installed the app
+var ErrWorkspaceNotInstalled = errors.New("workspace not installed")
+
+// Workspace is slack client and caches bound to an installed workspace
+type Workspace struct {
+	TeamID       string
+	Client       SlackAPI
+	BotUserID    string
+	EmojiManager *EmojiManager
+	Profiles     *ProfileCache
+}
+
+// WorkspaceManager resolves the workspace of incoming team id from the token store
+type WorkspaceManager struct {
+	Store           *TokenStore
+	NewClient       func(botToken string) SlackAPI
+	ProfileCacheTTL time.Duration
+	mutex           sync.Mutex
+	workspaces      map[string]*Workspace
+}
+
+// NewWorkspace creates workspace with its own emoji manager and profile cache
+func NewWorkspace(teamID string, client SlackAPI, botUserID string, profileCacheTTL time.Duration) *Workspace {
+	return &Workspace{
+		TeamID:       teamID,
+		Client:       client,
+		BotUserID:    botUserID,
+		EmojiManager: &EmojiManager{Client: client},
+		Profiles:     NewProfileCache(client, profileCacheTTL),
+	}
+}
+
+// Add registers the workspace which is not kept in the token store, like the one of SLACK_BOT_USER_TOKEN
+func (wm *WorkspaceManager) Add(workspace *Workspace) {
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+
+	if wm.workspaces == nil {
+		wm.workspaces = map[string]*Workspace{}
+	}
+	wm.workspaces[workspace.TeamID] = workspace
+}
+
+// Get returns the workspace of the team, creating it from the token store at the first time
+func (wm *WorkspaceManager) Get(teamID string) (*Workspace, error) {
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+
+	if workspace, ok := wm.workspaces[teamID]; ok {
+		return workspace, nil
+	}
+	if wm.Store == nil {
+		return nil, ErrWorkspaceNotInstalled
+	}
+	installation, ok := wm.Store.Get(teamID)
+	if !ok {
+		return nil, ErrWorkspaceNotInstalled
+	}
+
+	if wm.workspaces == nil {
+		wm.workspaces = map[string]*Workspace{}
+	}
+	workspace := NewWorkspace(teamID, wm.NewClient(installation.BotToken), installation.BotUserID, wm.ProfileCacheTTL)
+	wm.workspaces[teamID] = workspace
+	return workspace, nil
+}
+
+// Install saves installation and drops the workspace created with the previous token
+func (wm *WorkspaceManager) Install(installation Installation) error {
+	if err := wm.Store.Save(installation); err != nil {
+		return err
+	}
+
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+
+	delete(wm.workspaces, installation.TeamID)
+	return nil
+}
+
+// ForTeam returns copy of the handler bound to the workspace of the team
+func (handler *Handler) ForTeam(teamID string) (*Handler, error) {
+	if handler.Workspaces == nil {
+		return handler, nil
+	}
+
+	workspace, err := handler.Workspaces.Get(teamID)
+	if err != nil {
+		return nil, err
+	}
+	teamHandler := *handler
+	teamHandler.TeamID = workspace.TeamID
+	teamHandler.Client = workspace.Client
+	teamHandler.BotUserID = workspace.BotUserID
+	teamHandler.EmojiManager = workspace.EmojiManager
+	teamHandler.Profiles = workspace.Profiles
+	return &teamHandler, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"slack-waiter-bot/fakeslack"
+	"testing"
+	"time"
+)
+
+func TestHandlerForTeam(t *testing.T) {
+	tokenStore, err := NewTokenStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tokenStore.Save(Installation{TeamID: "T2", BotToken: "xoxb-2", BotUserID: "UBOT2"}); err != nil {
+		t.Fatal(err)
+	}
+	clients := map[string]*fakeslack.Slack{}
+	workspaces := &WorkspaceManager{
+		Store: tokenStore,
+		NewClient: func(botToken string) SlackAPI {
+			clients[botToken] = fakeslack.New("UBOT")
+			return clients[botToken]
+		},
+		ProfileCacheTTL: time.Minute,
+	}
+	// The workspace of the token given by the environment is not kept in the token store
+	first := fakeslack.New("UBOT1")
+	workspaces.Add(NewWorkspace("T1", first, "UBOT1", time.Minute))
+	handler, _ := newTestHandler(t)
+	handler.Workspaces = workspaces
+
+	teamHandler, err := handler.ForTeam("T1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if teamHandler.TeamID != "T1" || teamHandler.Client != first || teamHandler.BotUserID != "UBOT1" {
+		t.Errorf("handler of T1 = %s %s, want the added workspace", teamHandler.TeamID, teamHandler.BotUserID)
+	}
+
+	teamHandler, err = handler.ForTeam("T2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if teamHandler.TeamID != "T2" || teamHandler.Client != clients["xoxb-2"] || teamHandler.BotUserID != "UBOT2" {
+		t.Errorf("handler of T2 = %s %s, want the installed workspace", teamHandler.TeamID, teamHandler.BotUserID)
+	}
+	if teamHandler.Profiles.Client != clients["xoxb-2"] || teamHandler.EmojiManager.Client != clients["xoxb-2"] {
+		t.Error("caches of T2 are not bound to its client")
+	}
+	again, err := handler.ForTeam("T2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Profiles != teamHandler.Profiles || len(clients) != 1 {
+		t.Error("workspace of T2 is created again, want the cached one")
+	}
+	if handler.TeamID != "T1" || handler.Client == teamHandler.Client {
+		t.Error("handler is changed by the handler of the team")
+	}
+
+	// Reinstalling replaces the client with the new token
+	if err := workspaces.Install(Installation{TeamID: "T2", BotToken: "xoxb-2-new", BotUserID: "UBOT2"}); err != nil {
+		t.Fatal(err)
+	}
+	teamHandler, err = handler.ForTeam("T2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if teamHandler.Client != clients["xoxb-2-new"] {
+		t.Error("handler of T2 keeps the client of the old token")
+	}
+
+	if _, err := handler.ForTeam("T3"); err != ErrWorkspaceNotInstalled {
+		t.Errorf("err = %v, want %v", err, ErrWorkspaceNotInstalled)
+	}
+}